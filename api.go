@@ -129,7 +129,10 @@ type Bool interface {
 /**
 	Number interface
 
-    Numbers can be int64 and double
+    Numbers can be int64, double, big integer and decimal
+
+    Arithmetic promotes both operands to the widest type of them: LONG < BIGINT < DECIMAL < DOUBLE
+    Long results that overflow int64 are promoted to BIGINT
 
 */
 
@@ -210,6 +213,65 @@ type Number interface {
 	*/
 
 	Subtract(Number) Number
+
+	/**
+	Multiplies this number by the other one and return a new one
+	*/
+
+	Multiply(Number) Number
+
+	/**
+	Divides this number by the other one and return a new one
+
+	Integer division truncates toward zero, division by zero returns Nan
+	*/
+
+	Divide(Number) Number
+
+	/**
+	Divides this number by the other one in decimal and rounds result half-up to the precision,
+	see DivideRoundMode for other rounding modes
+	*/
+
+	DivideRound(Number, int32) Number
+
+	/**
+	Remainder of truncated division, has the sign of this number
+	*/
+
+	Mod(Number) Number
+
+	/**
+	Returns number with opposite sign
+	*/
+
+	Negate() Number
+
+	/**
+	Returns absolute value of the number
+	*/
+
+	Abs() Number
+
+	/**
+	Returns -1, 0 or 1 depending on the sign of the number, 0 for NaN
+	*/
+
+	Sign() int
+
+	/**
+	Demotes BigInt that fits int64 to Long, other numbers returned as is
+	*/
+
+	Normalize() Number
+
+	/**
+	Compares numbers of any types, returns -1, 0 or 1
+
+	NaN is equal to NaN and less than any other number
+	*/
+
+	Compare(Number) int
 }
 
 /**
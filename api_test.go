@@ -12,6 +12,10 @@ import (
 	val "github.com/codeallergy/value"
 	"github.com/stretchr/testify/require"
 	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing/iotest"
 	"time"
 	"runtime"
 	"fmt"
@@ -146,4 +150,91 @@ func TestPackNil(t *testing.T) {
 	require.Equal(t, val.Null, actual)
 
 
-}
\ No newline at end of file
+}
+
+func TestReadPlainReader(t *testing.T) {
+
+	m := testCreateMap()
+	mp, err := val.Pack(m)
+	require.Nil(t, err)
+
+	c, err := val.Read(iotest.OneByteReader(bytes.NewReader(mp)))
+	require.Nil(t, err)
+	require.True(t, m.Equal(c))
+
+	c, err = val.Read(iotest.HalfReader(bytes.NewReader(mp)))
+	require.Nil(t, err)
+	require.True(t, m.Equal(c))
+
+}
+
+func TestReadTruncated(t *testing.T) {
+
+	mp, err := val.Pack(testCreateMap())
+	require.Nil(t, err)
+
+	_, err = val.Read(iotest.OneByteReader(bytes.NewReader(mp[:len(mp)-2])))
+	require.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+	require.True(t, strings.Contains(err.Error(), "offset"))
+
+	_, err = val.Unpack(mp[:len(mp)-2], false)
+	require.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+	_, err = val.Read(iotest.OneByteReader(bytes.NewReader(nil)))
+	require.Equal(t, io.EOF, err)
+
+}
+
+func TestReadAt(t *testing.T) {
+
+	a := val.Utf8("first")
+	b := val.Long(12345)
+
+	buf := bytes.Buffer{}
+	require.Nil(t, val.Write(&buf, a))
+	require.Nil(t, val.Write(&buf, b))
+
+	r := bytes.NewReader(buf.Bytes())
+
+	c, off, err := val.ReadAt(r, 0)
+	require.Nil(t, err)
+	require.True(t, a.Equal(c))
+
+	c, off, err = val.ReadAt(r, off)
+	require.Nil(t, err)
+	require.True(t, b.Equal(c))
+	require.Equal(t, int64(buf.Len()), off)
+
+	_, _, err = val.ReadAt(r, off)
+	require.Equal(t, io.EOF, err)
+
+}
+
+func TestReadStreamPipe(t *testing.T) {
+
+	m := testCreateMap()
+	pr, pw := io.Pipe()
+
+	go func() {
+		for i:=0; i!=numIterations; i++ {
+			val.Write(pw, m)
+		}
+		pw.Close()
+	}()
+
+	valueC := make(chan val.Value)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- val.ReadStream(pr, valueC)
+	}()
+
+	cnt := 0
+	for v := range valueC {
+		require.True(t, m.Equal(v))
+		cnt++
+	}
+
+	require.Equal(t, numIterations, cnt)
+	require.Nil(t, <-errC)
+
+}
@@ -0,0 +1,246 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"math"
+	"math/big"
+)
+
+/**
+	Number type of the arithmetic result
+
+	Double is the widest one, because it is inexact and only it can hold NaN and infinities
+*/
+
+func promote(left, right Number) NumberType {
+	l, r := left.Type(), right.Type()
+	switch {
+	case l == DOUBLE || r == DOUBLE:
+		return DOUBLE
+	case l == DECIMAL || r == DECIMAL:
+		return DECIMAL
+	case l == BIGINT || r == BIGINT:
+		return BIGINT
+	default:
+		return LONG
+	}
+}
+
+func add(left, right Number) Number {
+	switch promote(left, right) {
+	case LONG:
+		return addLong(left.Long(), right.Long())
+	case BIGINT:
+		return BigInt(new(big.Int).Add(left.BigInt(), right.BigInt()))
+	case DECIMAL:
+		return Decimal(left.Decimal().Add(right.Decimal()))
+	default:
+		return Double(left.Double() + right.Double())
+	}
+}
+
+func subtract(left, right Number) Number {
+	switch promote(left, right) {
+	case LONG:
+		return subtractLong(left.Long(), right.Long())
+	case BIGINT:
+		return BigInt(new(big.Int).Sub(left.BigInt(), right.BigInt()))
+	case DECIMAL:
+		return Decimal(left.Decimal().Sub(right.Decimal()))
+	default:
+		return Double(left.Double() - right.Double())
+	}
+}
+
+func multiply(left, right Number) Number {
+	switch promote(left, right) {
+	case LONG:
+		return multiplyLong(left.Long(), right.Long())
+	case BIGINT:
+		return BigInt(new(big.Int).Mul(left.BigInt(), right.BigInt()))
+	case DECIMAL:
+		return Decimal(left.Decimal().Mul(right.Decimal()))
+	default:
+		return Double(left.Double() * right.Double())
+	}
+}
+
+func divide(left, right Number) Number {
+	t := promote(left, right)
+	if t != DOUBLE && right.Sign() == 0 {
+		return Nan
+	}
+	switch t {
+	case LONG:
+		return divideLong(left.Long(), right.Long())
+	case BIGINT:
+		return BigInt(new(big.Int).Quo(left.BigInt(), right.BigInt()))
+	case DECIMAL:
+		return Decimal(left.Decimal().Div(right.Decimal()))
+	default:
+		return Double(left.Double() / right.Double())
+	}
+}
+
+func divideRound(left, right Number, precision int32) Number {
+	return DivideRoundMode(left, right, precision, RoundHalfUp)
+}
+
+func mod(left, right Number) Number {
+	t := promote(left, right)
+	if t != DOUBLE && right.Sign() == 0 {
+		return Nan
+	}
+	switch t {
+	case LONG:
+		return Long(left.Long() % right.Long())
+	case BIGINT:
+		return BigInt(new(big.Int).Rem(left.BigInt(), right.BigInt()))
+	case DECIMAL:
+		_, r := left.Decimal().QuoRem(right.Decimal(), 0)
+		return Decimal(r)
+	default:
+		return Double(math.Mod(left.Double(), right.Double()))
+	}
+}
+
+/**
+	Long arithmetic never wraps around, results out of int64 range are promoted to BigInt
+*/
+
+func addLong(left, right int64) Number {
+	sum := left + right
+	if (left ^ sum) & (right ^ sum) < 0 {
+		return BigInt(new(big.Int).Add(big.NewInt(left), big.NewInt(right)))
+	}
+	return Long(sum)
+}
+
+func subtractLong(left, right int64) Number {
+	diff := left - right
+	if (left ^ right) & (left ^ diff) < 0 {
+		return BigInt(new(big.Int).Sub(big.NewInt(left), big.NewInt(right)))
+	}
+	return Long(diff)
+}
+
+func multiplyLong(left, right int64) Number {
+	if left == 0 || right == 0 {
+		return Zero
+	}
+	product := left * right
+	if product / right != left || (left == -1 && right == math.MinInt64) || (right == -1 && left == math.MinInt64) {
+		return BigInt(new(big.Int).Mul(big.NewInt(left), big.NewInt(right)))
+	}
+	return Long(product)
+}
+
+func divideLong(left, right int64) Number {
+	if left == math.MinInt64 && right == -1 {
+		return BigInt(new(big.Int).Neg(big.NewInt(left)))
+	}
+	return Long(left / right)
+}
+
+func negateLong(val int64) Number {
+	if val == math.MinInt64 {
+		return BigInt(new(big.Int).Neg(big.NewInt(val)))
+	}
+	return Long(-val)
+}
+
+/**
+	Exact comparison of numbers of any types
+*/
+
+func compareNumbers(left, right Number) int {
+
+	l, r := left.Type(), right.Type()
+
+	switch {
+	case l == LONG && r == LONG:
+		return compareLong(left.Long(), right.Long())
+	case l == DOUBLE && r == DOUBLE:
+		return compareDouble(left.Double(), right.Double())
+	case l != DOUBLE && r != DOUBLE:
+		if l == DECIMAL || r == DECIMAL {
+			return left.Decimal().Cmp(right.Decimal())
+		}
+		return left.BigInt().Cmp(right.BigInt())
+	}
+
+	// double with exact number
+	if left.IsNaN() {
+		return -1
+	}
+	if right.IsNaN() {
+		return 1
+	}
+	if l == DOUBLE && math.IsInf(left.Double(), 0) {
+		return int(math.Copysign(1, left.Double()))
+	}
+	if r == DOUBLE && math.IsInf(right.Double(), 0) {
+		return -int(math.Copysign(1, right.Double()))
+	}
+	return exactRat(left).Cmp(exactRat(right))
+}
+
+func compareLong(left, right int64) int {
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareDouble(left, right float64) int {
+	leftNaN, rightNaN := math.IsNaN(left), math.IsNaN(right)
+	switch {
+	case leftNaN || rightNaN:
+		return compareLong(boolToLong(rightNaN), boolToLong(leftNaN))
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func boolToLong(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+/**
+	Converts finite number to the rational without loss of precision
+*/
+
+func exactRat(n Number) *big.Rat {
+	switch n.Type() {
+	case LONG:
+		return new(big.Rat).SetInt64(n.Long())
+	case DOUBLE:
+		return new(big.Rat).SetFloat64(n.Double())
+	case DECIMAL:
+		d := n.Decimal()
+		exp := d.Exponent()
+		if exp >= 0 {
+			scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil)
+			return new(big.Rat).SetInt(scale.Mul(scale, d.Coefficient()))
+		}
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(-int64(exp)), nil)
+		return new(big.Rat).SetFrac(d.Coefficient(), scale)
+	default:
+		return new(big.Rat).SetInt(n.BigInt())
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"github.com/shopspring/decimal"
+	"math"
+	"math/big"
+)
+
+/**
+	Modes of the numeric equivalence
+*/
+
+type NumberEquality int
+
+const (
+	ExactEquality    NumberEquality = iota  // same mathematical value regardless of the type, transitive, NaN is never equal
+	TolerantEquality                        // doubles differ less than PrecisionLevel, not transitive
+)
+
+/**
+	Compares numbers in the mode, Equal on numbers always uses ExactEquality
+*/
+
+func NumberEqual(left, right Number, mode NumberEquality) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	if left.IsNaN() || right.IsNaN() {
+		return false
+	}
+	if mode == TolerantEquality {
+		if compareNumbers(left, right) == 0 {
+			return true
+		}
+		return math.Abs(left.Double() - right.Double()) < PrecisionLevel
+	}
+	return compareNumbers(left, right) == 0
+}
+
+/**
+	Returns the smallest lossless representation of the number, equal numbers have the same canonical form
+
+	Integers in int64 range become Long, other values exactly representable by float64 become Double,
+	other integers become BigInt and the rest become Decimal without trailing zeros.
+	NaN and infinities stay Double.
+*/
+
+func Canonical(n Number) Number {
+
+	switch n.Type() {
+	case LONG:
+		return n
+	case DOUBLE:
+		d := n.Double()
+		if math.IsNaN(d) || math.IsInf(d, 0) {
+			return n
+		}
+		if d == math.Trunc(d) && d >= math.MinInt64 && d < math.MaxInt64 {
+			return Long(int64(d))
+		}
+		return n
+	}
+
+	r := exactRat(n)
+	if r.IsInt() && r.Num().IsInt64() {
+		return Long(r.Num().Int64())
+	}
+	if f, exact := r.Float64(); exact {
+		return Double(f)
+	}
+	if r.IsInt() {
+		return BigInt(new(big.Int).Set(r.Num()))
+	}
+	return Decimal(stripTrailingZeros(n.Decimal()))
+}
+
+func stripTrailingZeros(d decimal.Decimal) decimal.Decimal {
+	coef, exp := d.Coefficient(), d.Exponent()
+	if coef.Sign() == 0 {
+		return decimal.Zero
+	}
+	ten := big.NewInt(10)
+	q, m := new(big.Int), new(big.Int)
+	for {
+		q.QuoRem(coef, ten, m)
+		if m.Sign() != 0 {
+			break
+		}
+		coef, q = q, coef
+		exp++
+	}
+	return decimal.NewFromBigInt(coef, exp)
+}
+
+/**
+	Numbers implement it to pack themselves without the canonical conversion
+*/
+
+type rawNumber interface {
+	packRaw(p Packer)
+}
+
+type canonicalPacker interface {
+	canonicalNumbers() bool
+}
+
+func packNumber(n rawNumber, p Packer) {
+	if c, ok := p.(canonicalPacker); ok && c.canonicalNumbers() {
+		Canonical(n.(Number)).(rawNumber).packRaw(p)
+	} else {
+		n.packRaw(p)
+	}
+}
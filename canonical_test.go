@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	"github.com/shopspring/decimal"
+	"math"
+	"math/big"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExactEquality(t *testing.T) {
+
+	one := []val.Number {
+		val.Long(1),
+		val.Double(1.0),
+		val.BigInt(big.NewInt(1)),
+		val.Decimal(decimal.RequireFromString("1.000")),
+	}
+
+	for _, a := range one {
+		for _, b := range one {
+			require.True(t, a.Equal(b), "%v %v", a, b)
+		}
+	}
+
+	require.False(t, val.Double(1.000001).Equal(val.Long(1)))
+	require.False(t, val.Long(1).Equal(val.Double(1.000001)))
+	require.False(t, val.Double(0.1).Equal(val.Decimal(decimal.RequireFromString("0.1"))))
+	require.False(t, val.Nan.Equal(val.Nan))
+	require.False(t, val.Long(1).Equal(val.Utf8("1")))
+
+	require.True(t, val.NumberEqual(val.Double(1.000001), val.Long(1), val.TolerantEquality))
+	require.False(t, val.NumberEqual(val.Double(1.000001), val.Long(1), val.ExactEquality))
+	require.False(t, val.NumberEqual(val.Nan, val.Nan, val.TolerantEquality))
+
+}
+
+func TestCanonical(t *testing.T) {
+
+	c := val.Canonical(val.Double(42))
+	require.Equal(t, val.LONG, c.Type())
+	require.Equal(t, int64(42), c.Long())
+
+	c = val.Canonical(val.Decimal(decimal.RequireFromString("42.000")))
+	require.Equal(t, val.LONG, c.Type())
+
+	c = val.Canonical(val.BigInt(big.NewInt(-7)))
+	require.Equal(t, val.LONG, c.Type())
+
+	c = val.Canonical(val.Decimal(decimal.RequireFromString("0.5")))
+	require.Equal(t, val.DOUBLE, c.Type())
+
+	c = val.Canonical(val.Decimal(decimal.RequireFromString("0.1000")))
+	require.Equal(t, val.DECIMAL, c.Type())
+	require.Equal(t, "0.1", c.Decimal().String())
+	require.Equal(t, int32(-1), c.Decimal().Exponent())
+
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	c = val.Canonical(val.BigInt(huge))
+	require.Equal(t, val.BIGINT, c.Type())
+
+	c = val.Canonical(val.BigInt(new(big.Int).Lsh(big.NewInt(1), 70)))
+	require.Equal(t, val.DOUBLE, c.Type())
+
+	require.True(t, val.Canonical(val.Nan).IsNaN())
+	require.Equal(t, val.DOUBLE, val.Canonical(val.Double(math.Inf(1))).Type())
+
+}
+
+func TestCanonicalPacking(t *testing.T) {
+
+	a := val.Map(val.ImmutableMapOf(map[string]val.Value {
+		"amount": val.Double(100),
+		"rate": val.Decimal(decimal.RequireFromString("0.250")),
+	}))
+
+	b := val.Map(val.ImmutableMapOf(map[string]val.Value {
+		"amount": val.Long(100),
+		"rate": val.Double(0.25),
+	}))
+
+	_, ha, err := val.Hash(a, crypto.SHA256)
+	require.NoError(t, err)
+	_, hb, err := val.Hash(b, crypto.SHA256)
+	require.NoError(t, err)
+	require.NotEqual(t, ha, hb)
+
+	_, ha, err = val.Hash(a, crypto.SHA256, val.WithCanonicalNumbers())
+	require.NoError(t, err)
+	_, hb, err = val.Hash(b, crypto.SHA256, val.WithCanonicalNumbers())
+	require.NoError(t, err)
+	require.Equal(t, ha, hb)
+
+	mp, err := val.Pack(val.Double(1), val.WithCanonicalNumbers())
+	require.NoError(t, err)
+	require.Equal(t, []byte{ 0x01 }, mp)
+
+}
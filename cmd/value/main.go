@@ -0,0 +1,439 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+/**
+	Command line tool for inspecting and converting packed values
+
+	value <command> [flags] [file ...]
+
+	Reads files or stdin when no files given, the input is a single value or
+	concatenated values written by value.WriteStream if -stream flag is set.
+
+	Private keys are never taken from the command line, seal and unseal read it
+	from the -keyfile or from the VALUE_KEY environment variable.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/md5"
+	"crypto/rand"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/codeallergy/value"
+	"github.com/pkg/errors"
+	_ "golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/nacl/box"
+	_ "golang.org/x/crypto/sha3"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+type command struct {
+	usage  string
+	run    func(e *env) error
+}
+
+/**
+	Invocation of the command with its flags and streams
+*/
+
+type env struct {
+	flags    *flag.FlagSet
+	stream   bool
+	hashAlg  string
+	peerKey  string
+	keyFile  string
+	getenv   func(string) string
+	stdin    io.Reader
+	stdout   io.Writer
+}
+
+const privateKeyEnv = "VALUE_KEY"
+
+var commands = map[string]*command {
+	"tojson":   {"print values as JSON, one per line", runToJSON},
+	"fromjson": {"pack JSON documents to values", runFromJSON},
+	"hex":      {"print packed values in hex, one per line", runHex},
+	"dump":     {"print annotated disassembly of packed values", runDump},
+	"hash":     {"print digest of every value, -alg selects the hash function", runHash},
+	"seal":     {"seal value for the recipient -peer with the private -keyfile", runSeal},
+	"unseal":   {"unseal value from the sender -peer with the private -keyfile", runUnseal},
+	"validate": {"check that input contains valid values", runValidate},
+	"keygen":   {"generate key pair for seal and unseal", runKeygen},
+}
+
+var hashAlgorithms = map[string]crypto.Hash {
+	"md5":         crypto.MD5,
+	"sha1":        crypto.SHA1,
+	"sha224":      crypto.SHA224,
+	"sha256":      crypto.SHA256,
+	"sha384":      crypto.SHA384,
+	"sha512":      crypto.SHA512,
+	"sha512-256":  crypto.SHA512_256,
+	"sha3-256":    crypto.SHA3_256,
+	"sha3-512":    crypto.SHA3_512,
+	"blake2b-256": crypto.BLAKE2b_256,
+	"blake2b-512": crypto.BLAKE2b_512,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, os.Getenv))
+}
+
+/**
+	Runs the command line without the program name, returns the exit code
+*/
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer, getenv func(string) string) int {
+
+	if len(args) < 1 {
+		usage(stderr)
+		return 2
+	}
+
+	name := args[0]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command '%s'\n", name)
+		usage(stderr)
+		return 2
+	}
+
+	e := &env{stdin: stdin, stdout: stdout, getenv: getenv}
+	e.flags = flag.NewFlagSet(name, flag.ContinueOnError)
+	e.flags.SetOutput(stderr)
+	e.flags.BoolVar(&e.stream, "stream", false, "input is a stream of concatenated values")
+	switch name {
+	case "hash":
+		e.flags.StringVar(&e.hashAlg, "alg", "sha256", "hash function: " + strings.Join(hashNames(), ", "))
+	case "seal", "unseal":
+		e.flags.StringVar(&e.peerKey, "peer", "", "hex public key of the recipient for seal or of the sender for unseal")
+		e.flags.StringVar(&e.keyFile, "keyfile", "", "file with hex private key of the sender for seal or of the recipient for unseal, " +
+			"default is the " + privateKeyEnv + " environment variable")
+	}
+	e.flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: value %s [flags] [file ...]\n\n%s\n\n", name, cmd.usage)
+		e.flags.PrintDefaults()
+	}
+	if err := e.flags.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	if err := cmd.run(e); err != nil {
+		fmt.Fprintf(stderr, "value %s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: value <command> [flags] [file ...]\n\ncommands:\n")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].usage)
+	}
+}
+
+func hashNames() []string {
+	var names []string
+	for name := range hashAlgorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/**
+	Reads all files in order or stdin if there are no arguments
+*/
+
+func readInput(e *env) ([]byte, error) {
+	if e.flags.NArg() == 0 {
+		return io.ReadAll(e.stdin)
+	}
+	var buf bytes.Buffer
+	for _, name := range e.flags.Args() {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+/**
+	Calls the function for every value in the input, single value input must not have trailing bytes
+*/
+
+func forEachValue(e *env, fn func(value.Value) error) error {
+
+	data, err := readInput(e)
+	if err != nil {
+		return err
+	}
+
+	unpacker := value.MessageUnpacker(data, false)
+	parser := value.MessageParser()
+
+	for cnt := 0; ; cnt++ {
+
+		val, err := value.Parse(unpacker, parser)
+		if err == io.EOF {
+			if cnt == 0 {
+				return errors.New("empty input")
+			}
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "value #%d", cnt)
+		}
+
+		if err := fn(val); err != nil {
+			return err
+		}
+
+		if !e.stream {
+			if rest := int64(len(data)) - unpacker.Offset(); rest > 0 {
+				return errors.Errorf("%d trailing bytes at offset %d, use -stream for concatenated values", rest, unpacker.Offset())
+			}
+			return nil
+		}
+	}
+}
+
+func runToJSON(e *env) error {
+	return forEachValue(e, func(val value.Value) error {
+		_, err := fmt.Fprintln(e.stdout, value.Jsonify(val))
+		return err
+	})
+}
+
+func runHex(e *env) error {
+	return forEachValue(e, func(val value.Value) error {
+		_, err := fmt.Fprintln(e.stdout, value.Hex(val))
+		return err
+	})
+}
+
+func runDump(e *env) error {
+	data, err := readInput(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(e.stdout, value.Dump(data))
+	return err
+}
+
+func runHash(e *env) error {
+	alg, ok := hashAlgorithms[e.hashAlg]
+	if !ok {
+		return errors.Errorf("unknown hash function '%s', supported: %s", e.hashAlg, strings.Join(hashNames(), ", "))
+	}
+	if !alg.Available() {
+		return errors.Errorf("hash function '%s' is not linked", e.hashAlg)
+	}
+	return forEachValue(e, func(val value.Value) error {
+		_, digest, err := value.Hash(val, alg)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(e.stdout, hex.EncodeToString(digest))
+		return err
+	})
+}
+
+func runValidate(e *env) error {
+	cnt := 0
+	err := forEachValue(e, func(val value.Value) error {
+		cnt++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(e.stdout, "ok, %d values\n", cnt)
+	return err
+}
+
+/**
+	JSON documents are converted by the same rules that value.Jsonify uses in reverse,
+	so numbers written as hex strings and base64 strings stay strings
+*/
+
+func runFromJSON(e *env) error {
+
+	data, err := readInput(e)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	for cnt := 0; ; cnt++ {
+
+		var doc interface{}
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			if cnt == 0 {
+				return errors.New("empty input")
+			}
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "document #%d", cnt)
+		}
+
+		if cnt > 0 && !e.stream {
+			return errors.New("multiple documents in the input, use -stream")
+		}
+
+		if err := value.Write(e.stdout, fromJSON(doc)); err != nil {
+			return err
+		}
+	}
+}
+
+func fromJSON(doc interface{}) value.Value {
+	switch v := doc.(type) {
+	case nil:
+		return value.Null
+	case bool:
+		return value.Boolean(v)
+	case json.Number:
+		return value.ParseNumber(v.String())
+	case string:
+		return value.ParseString(v)
+	case []interface{}:
+		list := make([]value.Value, len(v))
+		for i, el := range v {
+			list[i] = fromJSON(el)
+		}
+		return value.ImmutableList(list)
+	case map[string]interface{}:
+		m := make(map[string]value.Value, len(v))
+		for key, el := range v {
+			m[key] = fromJSON(el)
+		}
+		return value.ImmutableMapOf(m)
+	default:
+		return value.Utf8(fmt.Sprint(v))
+	}
+}
+
+func parseKey(source, s string) (*[32]byte, error) {
+	if s == "" {
+		return nil, errors.Errorf("%s is required", source)
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 32 {
+		return nil, errors.Errorf("%s must be 32 bytes in hex", source)
+	}
+	var key [32]byte
+	copy(key[:], b)
+	return &key, nil
+}
+
+/**
+	Reads private key from the -keyfile or from the environment, so it does not appear in the process list or shell history
+*/
+
+func (e *env) privateKey() (*[32]byte, error) {
+	if e.keyFile != "" {
+		data, err := os.ReadFile(e.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return parseKey("key file " + e.keyFile, strings.TrimSpace(string(data)))
+	}
+	return parseKey("flag -keyfile or " + privateKeyEnv + " environment variable", strings.TrimSpace(e.getenv(privateKeyEnv)))
+}
+
+/**
+	Single value is written as sealed bytes, stream is written as stream of sealed binary values
+*/
+
+func runSeal(e *env) error {
+	peer, err := parseKey("flag -peer", e.peerKey)
+	if err != nil {
+		return err
+	}
+	priv, err := e.privateKey()
+	if err != nil {
+		return err
+	}
+	return forEachValue(e, func(val value.Value) error {
+		sealed, err := value.Seal(val, peer, priv)
+		if err != nil {
+			return err
+		}
+		if e.stream {
+			return value.Write(e.stdout, value.Raw(sealed, false))
+		}
+		_, err = e.stdout.Write(sealed)
+		return err
+	})
+}
+
+func runUnseal(e *env) error {
+	peer, err := parseKey("flag -peer", e.peerKey)
+	if err != nil {
+		return err
+	}
+	priv, err := e.privateKey()
+	if err != nil {
+		return err
+	}
+
+	unseal := func(sealed []byte) error {
+		val, err := value.Unseal(sealed, peer, priv)
+		if err != nil {
+			return err
+		}
+		return value.Write(e.stdout, val)
+	}
+
+	if !e.stream {
+		data, err := readInput(e)
+		if err != nil {
+			return err
+		}
+		return unseal(data)
+	}
+
+	return forEachValue(e, func(val value.Value) error {
+		if val.Kind() != value.STRING {
+			return errors.Errorf("expected binary value in the stream, got %v", val.Kind())
+		}
+		return unseal(val.(value.String).Raw())
+	})
+}
+
+func runKeygen(e *env) error {
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(e.stdout, "public  %s\nprivate %s\n", hex.EncodeToString(pub[:]), hex.EncodeToString(priv[:]))
+	return err
+}
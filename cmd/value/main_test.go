@@ -0,0 +1,239 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"github.com/codeallergy/value"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/nacl/box"
+)
+
+func runCommand(t *testing.T, stdin []byte, args ...string) (int, string, string) {
+	return runCommandEnv(t, map[string]string{}, stdin, args...)
+}
+
+func runCommandEnv(t *testing.T, environ map[string]string, stdin []byte, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	getenv := func(name string) string {
+		return environ[name]
+	}
+	code := run(args, bytes.NewReader(stdin), &stdout, &stderr, getenv)
+	return code, stdout.String(), stderr.String()
+}
+
+func writeKeyFile(t *testing.T, key *[32]byte) string {
+	name := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(name, []byte(hex.EncodeToString(key[:]) + "\n"), 0600))
+	return name
+}
+
+func packed(t *testing.T, values ...value.Value) []byte {
+	var buf bytes.Buffer
+	for _, val := range values {
+		require.NoError(t, value.Write(&buf, val))
+	}
+	return buf.Bytes()
+}
+
+func TestUsage(t *testing.T) {
+
+	code, _, stderr := runCommand(t, nil)
+	require.Equal(t, 2, code)
+	require.Contains(t, stderr, "commands:")
+
+	code, _, stderr = runCommand(t, nil, "unknown")
+	require.Equal(t, 2, code)
+	require.Contains(t, stderr, "unknown command 'unknown'")
+
+	code, _, _ = runCommand(t, nil, "hex", "-wrong")
+	require.Equal(t, 2, code)
+
+}
+
+func TestSingleValue(t *testing.T) {
+
+	input := packed(t, value.Tuple(value.Long(1), value.Utf8("a")))
+
+	code, stdout, _ := runCommand(t, input, "hex")
+	require.Equal(t, 0, code)
+	require.Equal(t, "9201a161\n", stdout)
+
+	code, stdout, _ = runCommand(t, input, "tojson")
+	require.Equal(t, 0, code)
+	require.Equal(t, "[1,\"a\"]\n", stdout)
+
+	code, stdout, _ = runCommand(t, input, "validate")
+	require.Equal(t, 0, code)
+	require.Equal(t, "ok, 1 values\n", stdout)
+
+	code, stdout, _ = runCommand(t, input, "hash", "-alg", "sha256")
+	require.Equal(t, 0, code)
+	require.Len(t, strings.TrimSpace(stdout), 64)
+
+	code, _, stderr := runCommand(t, input, "hash", "-alg", "none")
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "unknown hash function 'none'")
+
+	code, stdout, _ = runCommand(t, input, "dump")
+	require.Equal(t, 0, code)
+	require.NotEmpty(t, stdout)
+
+}
+
+func TestStream(t *testing.T) {
+
+	input := packed(t, value.Long(1), value.Long(2))
+
+	code, _, stderr := runCommand(t, input, "hex")
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "1 trailing bytes at offset 1, use -stream")
+
+	code, stdout, _ := runCommand(t, input, "hex", "-stream")
+	require.Equal(t, 0, code)
+	require.Equal(t, "01\n02\n", stdout)
+
+	code, _, stderr = runCommand(t, nil, "validate")
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "empty input")
+
+	code, _, stderr = runCommand(t, []byte{0x92, 0x01}, "validate")
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "value #0")
+
+}
+
+func TestFiles(t *testing.T) {
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	require.NoError(t, os.WriteFile(first, packed(t, value.Long(1)), 0644))
+	require.NoError(t, os.WriteFile(second, packed(t, value.Long(2)), 0644))
+
+	code, stdout, _ := runCommand(t, nil, "hex", "-stream", first, second)
+	require.Equal(t, 0, code)
+	require.Equal(t, "01\n02\n", stdout)
+
+	code, _, stderr := runCommand(t, nil, "hex", filepath.Join(dir, "missing"))
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "missing")
+
+}
+
+func TestFromJSON(t *testing.T) {
+
+	code, stdout, _ := runCommand(t, []byte(`{"b": [1, 2.5, true, null], "a": "text"}`), "fromjson")
+	require.Equal(t, 0, code)
+
+	val, err := value.Unpack([]byte(stdout), true)
+	require.NoError(t, err)
+	require.Equal(t, `{"a": "text","b": [1,2.5,true,null]}`, value.Jsonify(val))
+
+	code, _, stderr := runCommand(t, []byte(`1 2`), "fromjson")
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "multiple documents in the input, use -stream")
+
+	code, stdout, _ = runCommand(t, []byte(`1 2`), "fromjson", "-stream")
+	require.Equal(t, 0, code)
+	require.Equal(t, []byte{0x01, 0x02}, []byte(stdout))
+
+	code, _, stderr = runCommand(t, []byte(`{`), "fromjson")
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "document #0")
+
+}
+
+func TestSealUnseal(t *testing.T) {
+
+	senderPub, senderPriv, err := box.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	recipientPub, recipientPriv, err := box.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	sealArgs := []string{"-peer", hex.EncodeToString(recipientPub[:]), "-keyfile", writeKeyFile(t, senderPriv)}
+	unsealArgs := []string{"-peer", hex.EncodeToString(senderPub[:]), "-keyfile", writeKeyFile(t, recipientPriv)}
+
+	// single value is written as sealed bytes
+	input := packed(t, value.Utf8("secret"))
+	code, sealed, _ := runCommand(t, input, append([]string{"seal"}, sealArgs...)...)
+	require.Equal(t, 0, code)
+
+	val, err := value.Unseal([]byte(sealed), senderPub, recipientPriv)
+	require.NoError(t, err)
+	require.Equal(t, "secret", val.String())
+
+	code, stdout, _ := runCommand(t, []byte(sealed), append([]string{"unseal"}, unsealArgs...)...)
+	require.Equal(t, 0, code)
+	require.Equal(t, input, []byte(stdout))
+
+	// stream is written as stream of sealed binary values
+	input = packed(t, value.Long(1), value.Long(2))
+	code, sealed, _ = runCommand(t, input, append([]string{"seal", "-stream"}, sealArgs...)...)
+	require.Equal(t, 0, code)
+
+	code, stdout, _ = runCommand(t, []byte(sealed), append([]string{"unseal", "-stream"}, unsealArgs...)...)
+	require.Equal(t, 0, code)
+	require.Equal(t, input, []byte(stdout))
+
+	code, _, stderr := runCommand(t, input, append([]string{"unseal", "-stream"}, unsealArgs...)...)
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "expected binary value in the stream")
+
+	code, _, stderr = runCommand(t, input, "seal", "-keyfile", sealArgs[3])
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "flag -peer is required")
+
+	code, _, stderr = runCommand(t, input, "seal", "-peer", "00", "-keyfile", sealArgs[3])
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "flag -peer must be 32 bytes in hex")
+
+	// private key is not accepted on the command line
+	code, _, _ = runCommand(t, input, "seal", "-peer", sealArgs[1], "-key", hex.EncodeToString(senderPriv[:]))
+	require.Equal(t, 2, code)
+
+	code, _, stderr = runCommand(t, input, "seal", "-peer", sealArgs[1])
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "flag -keyfile or VALUE_KEY environment variable is required")
+
+	code, _, stderr = runCommand(t, input, "seal", "-peer", sealArgs[1], "-keyfile", filepath.Join(t.TempDir(), "missing"))
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "missing")
+
+	bad := filepath.Join(t.TempDir(), "bad")
+	require.NoError(t, os.WriteFile(bad, []byte("00"), 0600))
+	code, _, stderr = runCommand(t, input, "seal", "-peer", sealArgs[1], "-keyfile", bad)
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "key file " + bad + " must be 32 bytes in hex")
+
+	// private key from the environment
+	input = packed(t, value.Utf8("secret"))
+	environ := map[string]string{ "VALUE_KEY": hex.EncodeToString(senderPriv[:]) }
+	code, sealed, _ = runCommandEnv(t, environ, input, "seal", "-peer", sealArgs[1])
+	require.Equal(t, 0, code)
+	val, err = value.Unseal([]byte(sealed), senderPub, recipientPriv)
+	require.NoError(t, err)
+	require.Equal(t, "secret", val.String())
+
+}
+
+func TestKeygen(t *testing.T) {
+
+	code, stdout, _ := runCommand(t, nil, "keygen")
+	require.Equal(t, 0, code)
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 2)
+	require.True(t, strings.HasPrefix(lines[0], "public  "))
+	require.True(t, strings.HasPrefix(lines[1], "private "))
+
+}
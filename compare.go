@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"bytes"
+	"sort"
+)
+
+/**
+	Compares values of any kinds, returns -1, 0 or 1
+
+	Defines total order NULL < BOOL < NUMBER < STRING < LIST < MAP < UNKNOWN, nil is the same as NULL.
+	Numbers are compared by value across number types, strings byte-wise and by the type on tie,
+	lists and maps lexicographically by elements and entries, string keys of maps are compared as Utf8, unknown extensions by packed bytes.
+*/
+
+func Compare(a, b Value) int {
+
+	ka, kb := kindOf(a), kindOf(b)
+	if ka != kb {
+		return compareLong(int64(ka), int64(kb))
+	}
+
+	switch ka {
+	case BOOL:
+		return compareLong(boolToLong(a.(Bool).Boolean()), boolToLong(b.(Bool).Boolean()))
+	case NUMBER:
+		return compareNumbers(a.(Number), b.(Number))
+	case STRING:
+		sa, sb := a.(String), b.(String)
+		if c := bytes.Compare(sa.Raw(), sb.Raw()); c != 0 {
+			return c
+		}
+		return compareLong(int64(sa.Type()), int64(sb.Type()))
+	case LIST:
+		return compareValues(a.(List).Values(), b.(List).Values())
+	case MAP:
+		return compareMaps(a.(Map), b.(Map))
+	case UNKNOWN:
+		return bytes.Compare(nativeOf(a), nativeOf(b))
+	default:
+		return 0
+	}
+}
+
+func kindOf(v Value) Kind {
+	if v == nil {
+		return NULL
+	}
+	return v.Kind()
+}
+
+func nativeOf(v Value) []byte {
+	if ext, ok := v.(Extension); ok {
+		return ext.Native()
+	}
+	b, _ := Pack(v)
+	return b
+}
+
+func compareValues(a, b []Value) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return compareLong(int64(len(a)), int64(len(b)))
+}
+
+func compareMaps(a, b Map) int {
+	keysA, valuesA := mapPairs(a)
+	keysB, valuesB := mapPairs(b)
+	for i := 0; i < len(keysA) && i < len(keysB); i++ {
+		if c := Compare(keysA[i], keysB[i]); c != 0 {
+			return c
+		}
+		if c := Compare(valuesA[i], valuesB[i]); c != 0 {
+			return c
+		}
+	}
+	return compareLong(int64(len(keysA)), int64(len(keysB)))
+}
+
+/**
+	Returns new immutable list with sorted values, the source list is not changed
+
+	Uses Compare if less function is nil, sort is stable
+*/
+
+func SortList(list List, less func(a, b Value) bool) List {
+	values := append([]Value(nil), list.Values()...)
+	if less == nil {
+		less = func(a, b Value) bool {
+			return Compare(a, b) < 0
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return less(values[i], values[j])
+	})
+	return ImmutableList(values)
+}
+
+/**
+	Returns new immutable list with values sorted in the reverse order of Compare
+*/
+
+func SortListDesc(list List) List {
+	return SortList(list, func(a, b Value) bool {
+		return Compare(a, b) > 0
+	})
+}
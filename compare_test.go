@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"github.com/shopspring/decimal"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareKinds(t *testing.T) {
+
+	ordered := []val.Value {
+		val.Null,
+		val.False,
+		val.True,
+		val.Nan,
+		val.Long(-1),
+		val.Double(0.5),
+		val.Decimal(decimal.RequireFromString("0.75")),
+		val.Long(1),
+		val.Utf8("a"),
+		val.Raw([]byte("a"), false),
+		val.Utf8("b"),
+		val.Tuple(val.Long(1)),
+		val.Tuple(val.Long(1), val.Long(2)),
+		val.Tuple(val.Long(2)),
+		val.ImmutableMapOf(map[string]val.Value { "a": val.Long(1) }),
+		val.ImmutableMapOf(map[string]val.Value { "a": val.Long(2) }),
+		val.ImmutableMapOf(map[string]val.Value { "b": val.Long(0) }),
+		val.Unknown([]byte{ 100, 1 }),
+	}
+
+	for i, a := range ordered {
+		for j, b := range ordered {
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			require.Equal(t, expected, val.Compare(a, b), "%d %v, %d %v", i, a, j, b)
+		}
+	}
+
+	require.Equal(t, 0, val.Compare(nil, val.Null))
+	require.Equal(t, 0, val.Compare(val.Long(1), val.Double(1)))
+
+}
+
+func TestSortList(t *testing.T) {
+
+	list := val.Tuple(val.Utf8("b"), val.Long(3), val.Null, val.Double(1.5), val.Utf8("a"), val.True)
+
+	sorted := val.SortList(list, nil)
+	require.Equal(t, `[null,true,1.5,3,"a","b"]`, val.Jsonify(sorted))
+	require.Equal(t, `["b",3,null,1.5,"a",true]`, val.Jsonify(list))
+
+	desc := val.SortListDesc(list)
+	require.Equal(t, `["b","a",3,1.5,true,null]`, val.Jsonify(desc))
+
+	byLen := val.SortList(val.Tuple(val.Utf8("ccc"), val.Utf8("a"), val.Utf8("bb")), func(a, b val.Value) bool {
+		return a.(val.String).Len() < b.(val.String).Len()
+	})
+	require.Equal(t, `["a","bb","ccc"]`, val.Jsonify(byLen))
+
+}
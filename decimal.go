@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"github.com/shopspring/decimal"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+/**
+	Rounding modes for decimal operations
+*/
+
+type RoundingMode int
+
+const (
+	RoundHalfEven RoundingMode = iota  // to nearest, ties to even digit, banker's rounding
+	RoundHalfUp                        // to nearest, ties away from zero
+	RoundDown                          // toward zero
+	RoundCeiling                       // toward positive infinity
+	RoundFloor                         // toward negative infinity
+)
+
+func (m RoundingMode) String() string {
+	switch m {
+	case RoundHalfEven:
+		return "half-even"
+	case RoundHalfUp:
+		return "half-up"
+	case RoundDown:
+		return "down"
+	case RoundCeiling:
+		return "ceiling"
+	case RoundFloor:
+		return "floor"
+	default:
+		return "unknown"
+	}
+}
+
+/**
+	Converts number to decimal, double is taken by the shortest decimal representation
+	that parses back to the same double, so 2.675 stays 2.675 and not 2.67499999...
+
+	Returns false for NaN and infinities
+*/
+
+func toDecimal(n Number) (decimal.Decimal, bool) {
+	if n.Type() == DOUBLE {
+		d := n.Double()
+		if math.IsNaN(d) || math.IsInf(d, 0) {
+			return decimal.Decimal{}, false
+		}
+		return decimal.NewFromFloat(d), true
+	}
+	return n.Decimal(), true
+}
+
+func roundDecimal(d decimal.Decimal, places int32, mode RoundingMode) decimal.Decimal {
+	if d.Exponent() >= -places {
+		return d
+	}
+	switch mode {
+	case RoundHalfUp:
+		return d.Round(places)
+	case RoundDown:
+		return d.RoundDown(places)
+	case RoundCeiling:
+		return d.RoundCeil(places)
+	case RoundFloor:
+		return d.RoundFloor(places)
+	default:
+		return d.RoundBank(places)
+	}
+}
+
+/**
+	Divides numbers in decimal and rounds the quotient to the precision by the mode,
+	the quotient is rounded once from the exact remainder, so no double rounding happens
+
+	Division by zero returns Nan, double operands give Double result
+*/
+
+func DivideRoundMode(left, right Number, precision int32, mode RoundingMode) Number {
+	if promote(left, right) == DOUBLE {
+		q := left.Double() / right.Double()
+		if math.IsNaN(q) || math.IsInf(q, 0) {
+			return Double(q)
+		}
+		d, _ := roundDecimal(decimal.NewFromFloat(q), precision, mode).Float64()
+		return Double(d)
+	}
+	if right.Sign() == 0 {
+		return Nan
+	}
+	return Decimal(divideDecimal(left.Decimal(), right.Decimal(), precision, mode))
+}
+
+func divideDecimal(a, b decimal.Decimal, precision int32, mode RoundingMode) decimal.Decimal {
+
+	// q is truncated toward zero, |r| < |b| * ulp
+	q, r := a.QuoRem(b, precision)
+	if r.Sign() == 0 {
+		return q
+	}
+
+	ulp := decimal.New(1, -precision)
+	if a.Sign() != b.Sign() {
+		ulp = ulp.Neg()
+	}
+
+	away := false
+	switch mode {
+	case RoundDown:
+	case RoundCeiling:
+		away = ulp.Sign() > 0
+	case RoundFloor:
+		away = ulp.Sign() < 0
+	default:
+		half := r.Abs().Mul(decimal.NewFromInt(2)).Cmp(b.Abs().Mul(ulp.Abs()))
+		if mode == RoundHalfUp {
+			away = half >= 0
+		} else {
+			away = half > 0 || (half == 0 && q.Shift(precision).BigInt().Bit(0) == 1)
+		}
+	}
+
+	if away {
+		return q.Add(ulp)
+	}
+	return q
+}
+
+/**
+	Rounds number to the places after the decimal point, negative places round the integer part
+
+	Result is Decimal, the trailing zeros are not added, NaN and infinities are returned as is
+*/
+
+func Round(n Number, places int32, mode RoundingMode) Number {
+	d, ok := toDecimal(n)
+	if !ok {
+		return n
+	}
+	return Decimal(roundDecimal(d, places, mode))
+}
+
+/**
+	Cuts digits after the places without rounding, same as Round with RoundDown mode
+*/
+
+func Truncate(n Number, places int32) Number {
+	return Round(n, places, RoundDown)
+}
+
+/**
+	Rounds number and sets exactly scale digits after the decimal point, adds trailing zeros if needed
+
+	Scale is preserved by Pack, so 12.50 and 12.5 have different encodings
+*/
+
+func Rescale(n Number, scale int32, mode RoundingMode) Number {
+	d, ok := toDecimal(n)
+	if !ok {
+		return n
+	}
+	d = roundDecimal(d, scale, mode)
+	if exp := d.Exponent(); exp > -scale {
+		shift := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp + scale)), nil)
+		d = decimal.NewFromBigInt(shift.Mul(shift, d.Coefficient()), -scale)
+	}
+	return Decimal(d)
+}
+
+/**
+	Formats number with exactly digits after the decimal point
+
+	Locale-independent: no grouping separators, '.' as decimal point, '-' for negative numbers
+*/
+
+func FormatFixed(n Number, digits int32, mode RoundingMode) string {
+	if digits < 0 {
+		digits = 0
+	}
+	d, ok := toDecimal(n)
+	if !ok {
+		return strconv.FormatFloat(n.Double(), 'f', -1, 64)
+	}
+	return roundDecimal(d, digits, mode).StringFixed(digits)
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"math"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func dec(s string) val.Number {
+	return val.Decimal(decimal.RequireFromString(s))
+}
+
+func TestRoundingModes(t *testing.T) {
+
+	cases := []struct {
+		input     string
+		expected  [5]string  // half-even, half-up, down, ceiling, floor
+	} {
+		{"2.345",  [5]string{"2.34", "2.35", "2.34", "2.35", "2.34"}},
+		{"2.355",  [5]string{"2.36", "2.36", "2.35", "2.36", "2.35"}},
+		{"-2.345", [5]string{"-2.34", "-2.35", "-2.34", "-2.34", "-2.35"}},
+		{"2.341",  [5]string{"2.34", "2.34", "2.34", "2.35", "2.34"}},
+		{"2.3",    [5]string{"2.3", "2.3", "2.3", "2.3", "2.3"}},
+	}
+
+	modes := []val.RoundingMode{val.RoundHalfEven, val.RoundHalfUp, val.RoundDown, val.RoundCeiling, val.RoundFloor}
+
+	for _, c := range cases {
+		for i, mode := range modes {
+			r := val.Round(dec(c.input), 2, mode)
+			require.Equal(t, val.DECIMAL, r.Type())
+			require.Equal(t, c.expected[i], r.Decimal().String(), "%s %v", c.input, mode)
+		}
+	}
+
+	require.Equal(t, "1200", val.Round(val.Long(1250), -2, val.RoundHalfEven).Decimal().String())
+	require.Equal(t, "1300", val.Round(val.Long(1250), -2, val.RoundHalfUp).Decimal().String())
+
+	// double is rounded by its shortest decimal representation
+	require.Equal(t, "2.68", val.Round(val.Double(2.675), 2, val.RoundHalfUp).Decimal().String())
+	require.True(t, val.Round(val.Nan, 2, val.RoundHalfUp).IsNaN())
+
+}
+
+func TestTruncateRescale(t *testing.T) {
+
+	require.Equal(t, "-12.34", val.Truncate(dec("-12.3499"), 2).Decimal().String())
+	require.Equal(t, "12", val.Truncate(val.Double(12.99), 0).Decimal().String())
+
+	r := val.Rescale(dec("12.5"), 2, val.RoundHalfEven)
+	require.Equal(t, int32(-2), r.Decimal().Exponent())
+	require.Equal(t, "12.5", r.Decimal().String())
+	require.True(t, dec("12.5").Equal(r))
+	require.NotEqual(t, val.Hex(dec("12.5")), val.Hex(r))
+
+	r = val.Rescale(val.Long(7), 3, val.RoundHalfEven)
+	require.Equal(t, int32(-3), r.Decimal().Exponent())
+
+	r = val.Rescale(dec("0.125"), 2, val.RoundHalfEven)
+	require.Equal(t, int32(-2), r.Decimal().Exponent())
+	require.Equal(t, "0.12", r.Decimal().String())
+
+}
+
+func TestFormatFixed(t *testing.T) {
+
+	require.Equal(t, "1234567.50", val.FormatFixed(dec("1234567.5"), 2, val.RoundHalfEven))
+	require.Equal(t, "-0.13", val.FormatFixed(dec("-0.125"), 2, val.RoundHalfUp))
+	require.Equal(t, "-0.12", val.FormatFixed(dec("-0.125"), 2, val.RoundHalfEven))
+	require.Equal(t, "100.000", val.FormatFixed(val.Long(100), 3, val.RoundHalfEven))
+	require.Equal(t, "3", val.FormatFixed(val.Double(2.5), 0, val.RoundHalfUp))
+	require.Equal(t, "340282366920938463463374607431768211456.00", val.FormatFixed(val.ParseNumber("0x0100000000000000000000000000000000"), 2, val.RoundDown))
+	require.Equal(t, "NaN", val.FormatFixed(val.Nan, 2, val.RoundDown))
+	require.Equal(t, "+Inf", val.FormatFixed(val.Double(math.Inf(1)), 2, val.RoundDown))
+
+}
+
+func TestDivideRoundMode(t *testing.T) {
+
+	cases := []struct {
+		left, right  int64
+		expected     [5]string  // half-even, half-up, down, ceiling, floor
+	} {
+		{1, 8,   [5]string{"0.12", "0.13", "0.12", "0.13", "0.12"}},
+		{3, 8,   [5]string{"0.38", "0.38", "0.37", "0.38", "0.37"}},
+		{-1, 8,  [5]string{"-0.12", "-0.13", "-0.12", "-0.12", "-0.13"}},
+		{2, -3,  [5]string{"-0.67", "-0.67", "-0.66", "-0.66", "-0.67"}},
+		{1, 4,   [5]string{"0.25", "0.25", "0.25", "0.25", "0.25"}},
+	}
+
+	modes := []val.RoundingMode{val.RoundHalfEven, val.RoundHalfUp, val.RoundDown, val.RoundCeiling, val.RoundFloor}
+
+	for _, c := range cases {
+		for i, mode := range modes {
+			q := val.DivideRoundMode(val.Long(c.left), val.Long(c.right), 2, mode)
+			require.Equal(t, val.DECIMAL, q.Type())
+			require.Equal(t, c.expected[i], q.Decimal().String(), "%d/%d %v", c.left, c.right, mode)
+		}
+	}
+
+	// rounded once, over-divide and Round would give 0.45
+	require.Equal(t, "0.44", val.DivideRoundMode(val.Long(4449), val.Long(10000), 2, val.RoundHalfUp).Decimal().String())
+	require.Equal(t, "0.45", val.Round(val.DivideRoundMode(val.Long(4449), val.Long(10000), 3, val.RoundHalfUp), 2, val.RoundHalfUp).Decimal().String())
+
+	require.Equal(t, "6.67", val.DivideRoundMode(dec("19.99"), val.Long(3), 2, val.RoundCeiling).Decimal().String())
+	require.Equal(t, 0.666, val.DivideRoundMode(val.Double(2), val.Long(3), 3, val.RoundDown).Double())
+	require.True(t, val.DivideRoundMode(val.Long(1), val.Long(0), 2, val.RoundDown).IsNaN())
+	require.True(t, math.IsInf(val.DivideRoundMode(val.Double(1), val.Long(0), 2, val.RoundDown).Double(), 1))
+
+}
@@ -0,0 +1,283 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+
+	mpCodeName = []string {
+
+		"mpNil",        // 0xc0
+		"mpNeverUsed",  // 0xc1
+		"mpFalse",      // 0xc2
+		"mpTrue",       // 0xc3
+
+		"mpBin8",       // 0xc4
+		"mpBin16",      // 0xc5
+		"mpBin32",      // 0xc6
+		"mpExt8",       // 0xc7
+		"mpExt16",      // 0xc8
+		"mpExt32",      // 0xc9
+
+		"mpFloat32",    // 0xca
+		"mpFloat64",    // 0xcb
+
+		"mpUint8",      // 0xcc
+		"mpUint16",     // 0xcd
+		"mpUint32",     // 0xce
+		"mpUint64",     // 0xcf
+
+		"mpInt8",       // 0xd0
+		"mpInt16",      // 0xd1
+		"mpInt32",      // 0xd2
+		"mpInt64",      // 0xd3
+
+		"mpFixExt1",    // 0xd4
+		"mpFixExt2",    // 0xd5
+		"mpFixExt4",    // 0xd6
+		"mpFixExt8",    // 0xd7
+		"mpFixExt16",   // 0xd8
+
+		"mpStr8",       // 0xd9
+		"mpStr16",      // 0xda
+		"mpStr32",      // 0xdb
+
+		"mpArray16",    // 0xdc
+		"mpArray32",    // 0xdd
+
+		"mpMap16",      // 0xde
+		"mpMap32",      // 0xdf
+
+	}
+
+	// max number of payload bytes printed for strings and binaries
+	dumpPreviewLen = 32
+
+	// max number of header bytes printed in the hex column
+	dumpHexLen = 9
+)
+
+func codeName(code byte) string {
+	switch {
+	case code <= mpPosFixIntMax:
+		return "mpPosFixInt"
+	case code <= mpFixMapMax:
+		return "mpFixMap"
+	case code <= mpFixArrayMax:
+		return "mpFixArray"
+	case code <= mpFixStrMax:
+		return "mpFixStr"
+	case code <= mpCodeMax:
+		return mpCodeName[code - mpCodeMin]
+	default:
+		return "mpNegFixInt"
+	}
+}
+
+/**
+	Disassembles packed values to the listing with offsets, codes, lengths and values
+
+	Flags non-canonical encodings, that this library would never produce, and truncated input
+*/
+
+func Dump(buf []byte) string {
+	d := &dumper{buf: buf, parser: MessageParser()}
+	off := 0
+	for off < len(buf) {
+		next, ok := d.dumpValue(off, 0)
+		if !ok {
+			break
+		}
+		off = next
+	}
+	return d.out.String()
+}
+
+type dumper struct {
+	buf     []byte
+	parser  *messageParser
+	out     strings.Builder
+	m       messageWriter
+}
+
+func (d *dumper) line(off int, header []byte, depth int, name string, detail string, flags ...string) {
+	hexStr := hex.EncodeToString(header)
+	if len(header) > dumpHexLen {
+		hexStr = hex.EncodeToString(header[:dumpHexLen]) + ".."
+	}
+	fmt.Fprintf(&d.out, "%08x  %-20s %s%-12s %s", off, hexStr, strings.Repeat("  ", depth), name, detail)
+	for _, flag := range flags {
+		d.out.WriteString(" [")
+		d.out.WriteString(flag)
+		d.out.WriteString("]")
+	}
+	d.out.WriteRune('\n')
+}
+
+func canonicalFlag(header, expected []byte) []string {
+	if bytes.Equal(header, expected) {
+		return nil
+	}
+	return []string{ "non-canonical, expected " + hex.EncodeToString(expected) }
+}
+
+/**
+	Prints value at the offset, returns offset of the next value and false if input is truncated
+*/
+
+func (d *dumper) dumpValue(off int, depth int) (int, bool) {
+
+	code := d.buf[off]
+	name := codeName(code)
+	format, size := nextFormat(code)
+
+	n := 1 + size
+	if off + n > len(d.buf) {
+		d.line(off, d.buf[off:], depth, name, fmt.Sprintf("header needs %d bytes, available %d", n, len(d.buf) - off), "truncated")
+		return len(d.buf), false
+	}
+
+	header := d.buf[off:off+n]
+	next := off + n
+
+	switch format {
+
+	case NilToken:
+		if code == mpNeverUsed {
+			d.line(off, header, depth, name, "nil", "reserved code")
+		} else {
+			d.line(off, header, depth, name, "nil")
+		}
+
+	case BoolToken:
+		d.line(off, header, depth, name, strconv.FormatBool(d.parser.ParseBool(header)))
+
+	case LongToken:
+		if code == mpUint64 && header[1] & 0x80 != 0 {
+			d.line(off, header, depth, name, strconv.FormatUint(binary.BigEndian.Uint64(header[1:]), 10), "overflows int64")
+		} else {
+			val := d.parser.ParseLong(header)
+			d.line(off, header, depth, name, strconv.FormatInt(val, 10), canonicalFlag(header, d.m.WriteLong(val))...)
+		}
+
+	case DoubleToken:
+		val := d.parser.ParseDouble(header)
+		d.line(off, header, depth, name, strconv.FormatFloat(val, 'g', -1, 64), canonicalFlag(header, d.m.WriteDouble(val))...)
+
+	case FixExtToken:
+		size, tagAndData := d.parser.ParseExt(header)
+		d.line(off, header, depth, name, d.describeExt(size, tagAndData))
+
+	case BinHeader, StrHeader, ExtHeader:
+		var size int
+		var expected []byte
+		switch format {
+		case BinHeader:
+			size = d.parser.ParseBin(header)
+			expected = d.m.WriteBinHeader(size)
+		case StrHeader:
+			size = d.parser.ParseStr(header)
+			expected = d.m.WriteStrHeader(size)
+		default:
+			size, _ = d.parser.ParseExt(header)
+			size++ // tag
+		}
+		if next + size > len(d.buf) {
+			d.line(off, header, depth, name, fmt.Sprintf("len=%d, available %d", size, len(d.buf) - next), "truncated")
+			return len(d.buf), false
+		}
+		payload := d.buf[next:next+size]
+		switch format {
+		case BinHeader:
+			d.line(off, header, depth, name, fmt.Sprintf("len=%d %s", size, previewHex(payload)), canonicalFlag(header, expected)...)
+		case StrHeader:
+			d.line(off, header, depth, name, fmt.Sprintf("len=%d %s", size, previewStr(payload)), canonicalFlag(header, expected)...)
+		default:
+			// header with the tag
+			header = d.buf[off:next+1]
+			expected = d.m.WriteExtHeader(size - 1, payload[0])
+			d.line(off, header, depth, name, d.describeExt(size - 1, payload), canonicalFlag(header, expected)...)
+		}
+		next += size
+
+	case ListHeader:
+		cnt := d.parser.ParseList(header)
+		d.line(off, header, depth, name, fmt.Sprintf("len=%d", cnt), canonicalFlag(header, d.m.WriteArrayHeader(cnt))...)
+		for i := 0; i < cnt; i++ {
+			if next >= len(d.buf) {
+				d.line(next, nil, depth + 1, "", fmt.Sprintf("missing %d of %d elements", cnt - i, cnt), "truncated")
+				return next, false
+			}
+			var ok bool
+			if next, ok = d.dumpValue(next, depth + 1); !ok {
+				return next, false
+			}
+		}
+
+	case MapHeader:
+		cnt := d.parser.ParseMap(header)
+		d.line(off, header, depth, name, fmt.Sprintf("len=%d", cnt), canonicalFlag(header, d.m.WriteMapHeader(cnt))...)
+		for i := 0; i < 2 * cnt; i++ {
+			if next >= len(d.buf) {
+				d.line(next, nil, depth + 1, "", fmt.Sprintf("missing %d of %d entries", cnt - i / 2, cnt), "truncated")
+				return next, false
+			}
+			var ok bool
+			if next, ok = d.dumpValue(next, depth + 1); !ok {
+				return next, false
+			}
+		}
+
+	}
+
+	return next, true
+}
+
+func (d *dumper) describeExt(size int, tagAndData []byte) string {
+	xtag := Ext(tagAndData[0])
+	detail := fmt.Sprintf("type=%d len=%d", int8(xtag), size)
+	switch xtag {
+	case BigIntExt, DecimalExt:
+		if val, err := doParseExt(tagAndData); err == nil {
+			detail += " " + val.String()
+		} else {
+			detail += " invalid: " + err.Error()
+		}
+	case EncryptedExt:
+		if alg, kid, err := EnvelopeInfo(tagAndData[1:]); err == nil {
+			detail += fmt.Sprintf(" encrypted %s key=%q", alg, kid)
+		} else {
+			detail += " invalid: " + err.Error()
+		}
+	case LinkExt:
+		detail += " link " + previewHex(tagAndData[1:])
+	default:
+		detail += " " + previewHex(tagAndData[1:])
+	}
+	return detail
+}
+
+func previewHex(b []byte) string {
+	if len(b) > dumpPreviewLen {
+		return hex.EncodeToString(b[:dumpPreviewLen]) + ".."
+	}
+	return hex.EncodeToString(b)
+}
+
+func previewStr(b []byte) string {
+	if len(b) > dumpPreviewLen {
+		return strconv.Quote(string(b[:dumpPreviewLen])) + ".."
+	}
+	return strconv.Quote(string(b))
+}
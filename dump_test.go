@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"strings"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDump(t *testing.T) {
+
+	mp, err := val.Pack(testCreateMap())
+	require.Nil(t, err)
+
+	dump := val.Dump(mp)
+
+	require.True(t, strings.HasPrefix(dump, "00000000  84"))
+	require.True(t, strings.Contains(dump, "mpFixMap     len=4"))
+	require.True(t, strings.Contains(dump, "mpFixArray   len=5"))
+	require.True(t, strings.Contains(dump, `mpFixStr     len=4 "name"`))
+	require.True(t, strings.Contains(dump, "mpFloat64    -12.34"))
+	require.True(t, strings.Contains(dump, "mpBin8       len=3 000102"))
+	require.False(t, strings.Contains(dump, "non-canonical"))
+	require.False(t, strings.Contains(dump, "truncated"))
+
+	dump = val.Dump(append(mp, []byte{0xcd, 0x00, 0x01}...))
+	require.True(t, strings.Contains(dump, "mpUint16     1 [non-canonical, expected 01]"))
+
+	dump = val.Dump(mp[:len(mp)-1])
+	require.True(t, strings.Contains(dump, "[truncated]"))
+
+	dump = val.Dump([]byte{0xd7, byte(val.DecimalExt), 0xff, 0xff, 0xff, 0xfd, 0x02, 0x01, 0xe2, 0xaf})
+	require.True(t, strings.Contains(dump, "mpFixExt8    type=2 len=8 0x01e2afx-03"))
+
+}
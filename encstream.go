@@ -0,0 +1,300 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/chacha20poly1305"
+	"io"
+	"math"
+)
+
+/**
+	Encrypted stream format, STREAM construction with XChaCha20-Poly1305
+
+	[magic "VALS"][version][nonce prefix 19]   header, additional data of every chunk
+	[uint32 length | final bit][ciphertext]      chunk
+
+	Chunk nonce is the prefix, uint32 chunk counter and the final flag byte, so reordered, dropped or
+	truncated chunks fail authentication, stream without the final chunk is reported as truncated.
+	Plaintext is the stream of packed values, a value may span chunks.
+*/
+
+const (
+	EncryptedStreamVersion byte = 1
+
+	encStreamPrefixSize = chacha20poly1305.NonceSizeX - 5
+	encStreamHeaderSize = 5 + encStreamPrefixSize
+	encStreamFinalBit   = uint32(1) << 31
+)
+
+var EncryptedStreamMagic = []byte("VALS")
+
+/**
+	Plaintext size of the chunks written by EncryptedStreamWriter
+*/
+
+var EncryptedChunkSize = 64 * 1024
+
+type encStream struct {
+	aead     cipher.AEAD
+	header   []byte
+	counter  uint64
+	nonce    [chacha20poly1305.NonceSizeX]byte
+}
+
+func newEncStream(key *[32]byte, header []byte) (encStream, error) {
+	aead, err := chacha20poly1305.NewX(key[:])
+	if err != nil {
+		return encStream{}, err
+	}
+	s := encStream{aead: aead, header: header}
+	copy(s.nonce[:], header[5:])
+	return s, nil
+}
+
+func (s *encStream) nextNonce(final bool) ([]byte, error) {
+	if s.counter > math.MaxUint32 {
+		return nil, errors.New("encrypted stream chunk counter overflow")
+	}
+	binary.BigEndian.PutUint32(s.nonce[encStreamPrefixSize:], uint32(s.counter))
+	s.nonce[len(s.nonce)-1] = 0
+	if final {
+		s.nonce[len(s.nonce)-1] = 1
+	}
+	s.counter++
+	return s.nonce[:], nil
+}
+
+/**
+	Writes values encrypted by the symmetric key in authenticated chunks
+
+	Close must be called to write the final chunk, without it the reader reports truncation. Not safe for concurrent use.
+*/
+
+type EncryptedStreamWriter struct {
+	w       io.Writer
+	stream  encStream
+	plain   bytes.Buffer
+	packer  *messagePacker
+	out     []byte
+	closed  bool
+	err     error
+}
+
+/**
+	Writes the stream header with the random nonce prefix
+*/
+
+func NewEncryptedStreamWriter(w io.Writer, key *[32]byte) (*EncryptedStreamWriter, error) {
+
+	header := make([]byte, encStreamHeaderSize)
+	copy(header, EncryptedStreamMagic)
+	header[4] = EncryptedStreamVersion
+	if _, err := io.ReadFull(rand.Reader, header[5:]); err != nil {
+		return nil, err
+	}
+
+	stream, err := newEncStream(key, header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	t := &EncryptedStreamWriter{w: w, stream: stream}
+	t.packer = MessagePacker(&t.plain)
+	return t, nil
+}
+
+func (t *EncryptedStreamWriter) Write(val Value) error {
+	if t.err != nil {
+		return t.err
+	}
+	if t.closed {
+		return errors.New("write to closed encrypted stream")
+	}
+	if val != nil {
+		val.Pack(t.packer)
+	} else {
+		t.packer.PackNil()
+	}
+	if err := t.packer.Error(); err != nil {
+		t.err = err
+		return err
+	}
+	for t.plain.Len() >= EncryptedChunkSize {
+		if err := t.writeChunk(t.plain.Next(EncryptedChunkSize), false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/**
+	Writes buffered values as a chunk, so the reader gets them without waiting for the full chunk
+*/
+
+func (t *EncryptedStreamWriter) Flush() error {
+	if t.err != nil || t.closed || t.plain.Len() == 0 {
+		return t.err
+	}
+	return t.writeChunk(t.plain.Next(t.plain.Len()), false)
+}
+
+/**
+	Writes the final chunk, does not close the underlying writer
+*/
+
+func (t *EncryptedStreamWriter) Close() error {
+	if t.err != nil || t.closed {
+		return t.err
+	}
+	t.closed = true
+	return t.writeChunk(t.plain.Next(t.plain.Len()), true)
+}
+
+func (t *EncryptedStreamWriter) writeChunk(plain []byte, final bool) error {
+	nonce, err := t.stream.nextNonce(final)
+	if err != nil {
+		t.err = err
+		return err
+	}
+	prefix := uint32(len(plain) + t.stream.aead.Overhead())
+	if final {
+		prefix |= encStreamFinalBit
+	}
+	t.out = append(t.out[:0], 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(t.out, prefix)
+	t.out = t.stream.aead.Seal(t.out, nonce, plain, t.stream.header)
+	_, t.err = t.w.Write(t.out)
+	return t.err
+}
+
+/**
+	Reads values written by EncryptedStreamWriter
+
+	Next returns io.EOF only after the authenticated final chunk, ErrUnseal wrapped errors on
+	tampering and io.ErrUnexpectedEOF if the stream is truncated. Not safe for concurrent use.
+*/
+
+type EncryptedStreamReader struct {
+	values  *StreamReader
+}
+
+type encChunkReader struct {
+	r       io.Reader
+	stream  encStream
+	buf     []byte
+	plain   []byte
+	final   bool
+	err     error
+}
+
+/**
+	Reads and checks the stream header
+*/
+
+func NewEncryptedStreamReader(r io.Reader, key *[32]byte) (*EncryptedStreamReader, error) {
+
+	header := make([]byte, encStreamHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.Wrap(ErrUnseal, "encrypted stream header")
+	}
+	if !bytes.Equal(header[:4], EncryptedStreamMagic) {
+		return nil, errors.Wrap(ErrUnseal, "not an encrypted stream")
+	}
+	if header[4] != EncryptedStreamVersion {
+		return nil, errors.Wrapf(ErrUnseal, "unsupported encrypted stream version %d", header[4])
+	}
+
+	stream, err := newEncStream(key, header)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := &encChunkReader{r: r, stream: stream}
+	return &EncryptedStreamReader{values: NewStreamReader(chunks)}, nil
+}
+
+func (t *EncryptedStreamReader) Next() (Value, error) {
+	return t.values.Next()
+}
+
+func (t *encChunkReader) Read(p []byte) (int, error) {
+	for len(t.plain) == 0 {
+		if t.err != nil {
+			return 0, t.err
+		}
+		if t.final {
+			t.err = t.checkTrailing()
+			continue
+		}
+		t.err = t.readChunk()
+	}
+	n := copy(p, t.plain)
+	t.plain = t.plain[n:]
+	return n, nil
+}
+
+func (t *encChunkReader) checkTrailing() error {
+	var b [1]byte
+	for {
+		n, err := t.r.Read(b[:])
+		if n > 0 {
+			return errors.Wrap(ErrUnseal, "data after the final chunk")
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (t *encChunkReader) readChunk() error {
+
+	var prefix [4]byte
+	if _, err := io.ReadFull(t.r, prefix[:]); err != nil {
+		if err == io.EOF {
+			return errors.Wrap(io.ErrUnexpectedEOF, "encrypted stream without the final chunk")
+		}
+		return err
+	}
+
+	v := binary.BigEndian.Uint32(prefix[:])
+	final := v & encStreamFinalBit != 0
+	size := int(v &^ encStreamFinalBit)
+	if size < t.stream.aead.Overhead() || size > MaxFrameSize {
+		return errors.Wrapf(ErrUnseal, "invalid chunk length %d", size)
+	}
+
+	if cap(t.buf) < size {
+		t.buf = make([]byte, size)
+	}
+	t.buf = t.buf[:size]
+	if _, err := io.ReadFull(t.r, t.buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return errors.Wrap(err, "encrypted stream chunk")
+	}
+
+	nonce, err := t.stream.nextNonce(final)
+	if err != nil {
+		return err
+	}
+	plain, err := t.stream.aead.Open(t.buf[:0], nonce, t.buf, t.stream.header)
+	if err != nil {
+		return errors.Wrapf(ErrUnseal, "chunk %d authentication failed", t.stream.counter - 1)
+	}
+	t.plain = plain
+	t.final = final
+	return nil
+}
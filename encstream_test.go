@@ -0,0 +1,192 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func writeEncryptedStream(t *testing.T, key *[32]byte, n int) []byte {
+	var buf bytes.Buffer
+	w, err := val.NewEncryptedStreamWriter(&buf, key)
+	require.NoError(t, err)
+	for i := 0; i < n; i++ {
+		require.NoError(t, w.Write(val.Tuple(val.Long(int64(i)), val.Utf8("payload payload payload"))))
+	}
+	require.NoError(t, w.Close())
+	require.Error(t, w.Write(val.Null))
+	return buf.Bytes()
+}
+
+func readEncryptedStream(key *[32]byte, data []byte) (int, error) {
+	r, err := val.NewEncryptedStreamReader(bytes.NewReader(data), key)
+	if err != nil {
+		return 0, err
+	}
+	cnt := 0
+	for {
+		v, err := r.Next()
+		if err == io.EOF {
+			return cnt, nil
+		}
+		if err != nil {
+			return cnt, err
+		}
+		if v.(val.List).GetNumberAt(0).Long() != int64(cnt) {
+			return cnt, errors.New("out of order")
+		}
+		cnt++
+	}
+}
+
+func TestEncryptedStream(t *testing.T) {
+
+	old := val.EncryptedChunkSize
+	val.EncryptedChunkSize = 100
+	defer func() { val.EncryptedChunkSize = old }()
+
+	key := randomKey()
+
+	data := writeEncryptedStream(t, key, 50)
+	cnt, err := readEncryptedStream(key, data)
+	require.NoError(t, err)
+	require.Equal(t, 50, cnt)
+
+	empty := writeEncryptedStream(t, key, 0)
+	cnt, err = readEncryptedStream(key, empty)
+	require.NoError(t, err)
+	require.Equal(t, 0, cnt)
+
+	_, err = readEncryptedStream(randomKey(), data)
+	require.True(t, errors.Is(err, val.ErrUnseal))
+
+	// truncated at the chunk boundary, 24 bytes header, 4 bytes prefix, 100 bytes chunk and 16 bytes tag
+	_, err = readEncryptedStream(key, data[:24 + 2 * (4 + 100 + 16)])
+	require.True(t, errors.Is(err, io.ErrUnexpectedEOF), "%v", err)
+
+	// truncated in the middle
+	_, err = readEncryptedStream(key, data[:len(data) - 5])
+	require.Error(t, err)
+
+	// reordered chunks
+	chunk := 4 + 100 + 16
+	reordered := append([]byte(nil), data...)
+	copy(reordered[24:24+chunk], data[24+chunk:24+2*chunk])
+	copy(reordered[24+chunk:24+2*chunk], data[24:24+chunk])
+	_, err = readEncryptedStream(key, reordered)
+	require.True(t, errors.Is(err, val.ErrUnseal))
+
+	// final flag moved to an earlier chunk
+	early := append([]byte(nil), data[:24+chunk]...)
+	early[24] |= 0x80
+	_, err = readEncryptedStream(key, early)
+	require.True(t, errors.Is(err, val.ErrUnseal))
+
+	// trailing data after the final chunk
+	_, err = readEncryptedStream(key, append(append([]byte(nil), data...), 0))
+	require.True(t, errors.Is(err, val.ErrUnseal))
+
+}
+
+func TestEncryptedStreamFlush(t *testing.T) {
+
+	key := randomKey()
+	var buf bytes.Buffer
+	w, err := val.NewEncryptedStreamWriter(&buf, key)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Write(val.Utf8("first")))
+	require.NoError(t, w.Flush())
+
+	// flushed value is readable before the stream is closed
+	r, err := val.NewEncryptedStreamReader(bytes.NewReader(buf.Bytes()), key)
+	require.NoError(t, err)
+	v, err := r.Next()
+	require.NoError(t, err)
+	require.Equal(t, "first", v.String())
+
+	require.NoError(t, w.Write(val.Utf8("second")))
+	require.NoError(t, w.Close())
+
+	cnt := 0
+	r, err = val.NewEncryptedStreamReader(bytes.NewReader(buf.Bytes()), key)
+	require.NoError(t, err)
+	for {
+		_, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		cnt++
+	}
+	require.Equal(t, 2, cnt)
+
+}
+
+/**
+	Returns (0, nil) on every other read and the tail after the data is drained
+*/
+
+type stallingReader struct {
+	data   []byte
+	tail   []byte
+	err    error
+	stall  bool
+}
+
+func (t *stallingReader) Read(p []byte) (int, error) {
+	t.stall = !t.stall
+	if t.stall {
+		return 0, nil
+	}
+	if len(t.data) == 0 {
+		t.data, t.tail = t.tail, nil
+		if len(t.data) == 0 {
+			return 0, t.err
+		}
+	}
+	n := copy(p, t.data)
+	t.data = t.data[n:]
+	return n, nil
+}
+
+func TestEncryptedStreamTrailing(t *testing.T) {
+
+	key := randomKey()
+	data := writeEncryptedStream(t, key, 3)
+
+	readAll := func(r io.Reader) (int, error) {
+		sr, err := val.NewEncryptedStreamReader(r, key)
+		require.NoError(t, err)
+		cnt := 0
+		for {
+			_, err := sr.Next()
+			if err != nil {
+				return cnt, err
+			}
+			cnt++
+		}
+	}
+
+	cnt, err := readAll(&stallingReader{data: data, err: io.EOF})
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 3, cnt)
+
+	// empty reads do not hide the trailing data
+	_, err = readAll(&stallingReader{data: data, tail: []byte{0}, err: io.EOF})
+	require.True(t, errors.Is(err, val.ErrUnseal), "%v", err)
+
+	// read error after the final chunk is not reported as the end of stream
+	failure := errors.New("connection reset")
+	_, err = readAll(&stallingReader{data: data, err: failure})
+	require.True(t, errors.Is(err, failure), "%v", err)
+
+}
@@ -0,0 +1,303 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/chacha20poly1305"
+	"io"
+	"sort"
+	"sync"
+)
+
+/**
+	Encryption envelope format
+
+	[magic "VALE"][version][alg][key id len][key id][nonce][ciphertext]
+
+	Header before the nonce is authenticated as additional data, so version, algorithm and key id
+	can not be changed without failing decryption
+*/
+
+const (
+	EnvelopeVersion byte = 1
+
+	envelopeFixedSize = 7
+	maxKeyIdLen = 255
+)
+
+var EnvelopeMagic = []byte("VALE")
+
+type EnvelopeAlg byte
+
+const (
+	XChaCha20Poly1305 EnvelopeAlg = iota + 1
+	AES256GCM
+)
+
+func (a EnvelopeAlg) String() string {
+	switch a {
+	case XChaCha20Poly1305:
+		return "xchacha20-poly1305"
+	case AES256GCM:
+		return "aes256-gcm"
+	default:
+		return "unknown"
+	}
+}
+
+/**
+	Algorithm used by SealEnvelope and Reseal
+*/
+
+var DefaultEnvelopeAlg = XChaCha20Poly1305
+
+var ErrUnknownKey = errors.New("unknown key")
+
+func (a EnvelopeAlg) aead(key *[32]byte) (cipher.AEAD, error) {
+	switch a {
+	case XChaCha20Poly1305:
+		return chacha20poly1305.NewX(key[:])
+	case AES256GCM:
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	default:
+		return nil, errors.Errorf("unsupported envelope algorithm %d", a)
+	}
+}
+
+/**
+	Keys by id for envelopes, primary key encrypts new envelopes, all keys decrypt
+*/
+
+type Keyring interface {
+
+	/**
+	Returns id and key for new envelopes
+	*/
+
+	PrimaryKey() (string, *[32]byte, error)
+
+	/**
+	Returns key by the id or ErrUnknownKey
+	*/
+
+	Key(kid string) (*[32]byte, error)
+}
+
+/**
+	Thread-safe in-memory keyring
+*/
+
+type MemoryKeyring struct {
+	mu       sync.RWMutex
+	keys     map[string]*[32]byte
+	primary  string
+}
+
+func NewKeyring() *MemoryKeyring {
+	return &MemoryKeyring{keys: make(map[string]*[32]byte)}
+}
+
+/**
+	Adds or replaces the key, the first added key becomes primary
+*/
+
+func (t *MemoryKeyring) Add(kid string, key *[32]byte) error {
+	if len(kid) > maxKeyIdLen {
+		return errors.Errorf("key id is longer than %d bytes", maxKeyIdLen)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.keys[kid] = key
+	if len(t.keys) == 1 {
+		t.primary = kid
+	}
+	return nil
+}
+
+func (t *MemoryKeyring) SetPrimary(kid string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.keys[kid]; !ok {
+		return errors.Wrapf(ErrUnknownKey, "key id '%s'", kid)
+	}
+	t.primary = kid
+	return nil
+}
+
+/**
+	Removes the key, envelopes sealed by it can not be opened anymore
+*/
+
+func (t *MemoryKeyring) Remove(kid string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.keys, kid)
+	if t.primary == kid {
+		t.primary = ""
+	}
+}
+
+func (t *MemoryKeyring) KeyIds() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var ids []string
+	for kid := range t.keys {
+		ids = append(ids, kid)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (t *MemoryKeyring) PrimaryKey() (string, *[32]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	key, ok := t.keys[t.primary]
+	if !ok {
+		return "", nil, errors.Wrap(ErrUnknownKey, "no primary key")
+	}
+	return t.primary, key, nil
+}
+
+func (t *MemoryKeyring) Key(kid string) (*[32]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	key, ok := t.keys[kid]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownKey, "key id '%s'", kid)
+	}
+	return key, nil
+}
+
+/**
+	Seals value with the primary key of the keyring and DefaultEnvelopeAlg
+*/
+
+func SealEnvelope(val Value, keyring Keyring) ([]byte, error) {
+	kid, key, err := keyring.PrimaryKey()
+	if err != nil {
+		return nil, err
+	}
+	return SealEnvelopeWith(val, DefaultEnvelopeAlg, kid, key)
+}
+
+func SealEnvelopeWith(val Value, alg EnvelopeAlg, kid string, key *[32]byte) ([]byte, error) {
+	unencrypted, err := Pack(val)
+	if err != nil {
+		return nil, err
+	}
+	return sealEnvelope(unencrypted, alg, kid, key)
+}
+
+func sealEnvelope(unencrypted []byte, alg EnvelopeAlg, kid string, key *[32]byte) ([]byte, error) {
+	if len(kid) > maxKeyIdLen {
+		return nil, errors.Errorf("key id is longer than %d bytes", maxKeyIdLen)
+	}
+	aead, err := alg.aead(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, envelopeFixedSize + len(kid))
+	header = append(header, EnvelopeMagic...)
+	header = append(header, EnvelopeVersion, byte(alg), byte(len(kid)))
+	header = append(header, kid...)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	// header is the additional data, it must not share memory with the output
+	out := make([]byte, 0, len(header) + len(nonce) + len(unencrypted) + aead.Overhead())
+	out = append(out, header...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, unencrypted, header), nil
+}
+
+/**
+	Returns algorithm and key id of the envelope without decryption
+*/
+
+func EnvelopeInfo(encrypted []byte) (EnvelopeAlg, string, error) {
+	alg, kid, _, err := parseEnvelopeHeader(encrypted)
+	return alg, kid, err
+}
+
+func parseEnvelopeHeader(b []byte) (EnvelopeAlg, string, int, error) {
+	if len(b) < envelopeFixedSize || !bytes.Equal(b[:len(EnvelopeMagic)], EnvelopeMagic) {
+		return 0, "", 0, errors.Wrap(ErrUnseal, "not an envelope")
+	}
+	if b[4] != EnvelopeVersion {
+		return 0, "", 0, errors.Wrapf(ErrUnseal, "unsupported envelope version %d", b[4])
+	}
+	alg := EnvelopeAlg(b[5])
+	n := envelopeFixedSize + int(b[6])
+	if len(b) < n {
+		return 0, "", 0, errors.Wrap(ErrUnseal, "truncated envelope header")
+	}
+	return alg, string(b[envelopeFixedSize:n]), n, nil
+}
+
+func openEnvelope(encrypted []byte, keyring Keyring) ([]byte, error) {
+	alg, kid, n, err := parseEnvelopeHeader(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keyring.Key(kid)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := alg.aead(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(encrypted) < n + aead.NonceSize() {
+		return nil, errors.Wrap(ErrUnseal, "truncated envelope nonce")
+	}
+	nonce := encrypted[n:n+aead.NonceSize()]
+	decrypted, err := aead.Open(nil, nonce, encrypted[n+aead.NonceSize():], encrypted[:n])
+	if err != nil {
+		return nil, ErrUnseal
+	}
+	return decrypted, nil
+}
+
+/**
+	Unseals envelope with the key found in the keyring by the key id from the header
+*/
+
+func UnsealEnvelope(encrypted []byte, keyring Keyring) (Value, error) {
+	decrypted, err := openEnvelope(encrypted, keyring)
+	if err != nil {
+		return nil, err
+	}
+	return Unpack(decrypted, false)
+}
+
+/**
+	Re-encrypts envelope with the primary key and DefaultEnvelopeAlg, payload bytes are kept as is
+*/
+
+func Reseal(encrypted []byte, keyring Keyring) ([]byte, error) {
+	decrypted, err := openEnvelope(encrypted, keyring)
+	if err != nil {
+		return nil, err
+	}
+	kid, key, err := keyring.PrimaryKey()
+	if err != nil {
+		return nil, err
+	}
+	return sealEnvelope(decrypted, DefaultEnvelopeAlg, kid, key)
+}
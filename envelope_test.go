@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"crypto/rand"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func randomKey() *[32]byte {
+	var key [32]byte
+	rand.Read(key[:])
+	return &key
+}
+
+func TestEnvelope(t *testing.T) {
+
+	keyring := val.NewKeyring()
+	require.NoError(t, keyring.Add("2023-01", randomKey()))
+
+	doc := val.EmptyImmutableMap().Put("card", val.Utf8("4111"))
+
+	sealed, err := val.SealEnvelope(doc, keyring)
+	require.NoError(t, err)
+
+	alg, kid, err := val.EnvelopeInfo(sealed)
+	require.NoError(t, err)
+	require.Equal(t, val.XChaCha20Poly1305, alg)
+	require.Equal(t, "2023-01", kid)
+
+	v, err := val.UnsealEnvelope(sealed, keyring)
+	require.NoError(t, err)
+	require.True(t, doc.Equal(v))
+
+	// header is authenticated
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(val.EnvelopeMagic) + 3] = '3'
+	require.NoError(t, keyring.Add("3023-01", mustKey(t, keyring, "2023-01")))
+	_, err = val.UnsealEnvelope(tampered, keyring)
+	require.Equal(t, val.ErrUnseal, err)
+
+	_, err = val.UnsealEnvelope(sealed, val.NewKeyring())
+	require.True(t, errors.Is(err, val.ErrUnknownKey))
+
+	_, err = val.UnsealEnvelope(sealed[:5], keyring)
+	require.True(t, errors.Is(err, val.ErrUnseal))
+
+	gcm, err := val.SealEnvelopeWith(doc, val.AES256GCM, "2023-01", mustKey(t, keyring, "2023-01"))
+	require.NoError(t, err)
+	v, err = val.UnsealEnvelope(gcm, keyring)
+	require.NoError(t, err)
+	require.True(t, doc.Equal(v))
+
+}
+
+func TestReseal(t *testing.T) {
+
+	keyring := val.NewKeyring()
+	require.NoError(t, keyring.Add("old", randomKey()))
+
+	doc := val.Tuple(val.Long(1), val.Utf8("x"))
+	sealed, err := val.SealEnvelope(doc, keyring)
+	require.NoError(t, err)
+
+	require.NoError(t, keyring.Add("new", randomKey()))
+	require.NoError(t, keyring.SetPrimary("new"))
+	require.Equal(t, []string{ "new", "old" }, keyring.KeyIds())
+
+	resealed, err := val.Reseal(sealed, keyring)
+	require.NoError(t, err)
+	_, kid, err := val.EnvelopeInfo(resealed)
+	require.NoError(t, err)
+	require.Equal(t, "new", kid)
+
+	keyring.Remove("old")
+	_, err = val.UnsealEnvelope(sealed, keyring)
+	require.True(t, errors.Is(err, val.ErrUnknownKey))
+
+	v, err := val.UnsealEnvelope(resealed, keyring)
+	require.NoError(t, err)
+	require.True(t, doc.Equal(v))
+
+	require.True(t, errors.Is(keyring.SetPrimary("old"), val.ErrUnknownKey))
+
+}
+
+func mustKey(t *testing.T, keyring val.Keyring, kid string) *[32]byte {
+	key, err := keyring.Key(kid)
+	require.NoError(t, err)
+	return key
+}
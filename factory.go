@@ -7,7 +7,7 @@ package value
 
 func EmptyList(immutable bool) List {
 	if immutable {
-		return immutableListValue([]Value{})
+		return newImmutableList([]Value{})
 	} else {
 		return solidListValue([]Value{})
 	}
@@ -15,7 +15,7 @@ func EmptyList(immutable bool) List {
 
 func EmptyMap(immutable bool) Map {
 	if immutable {
-		return immutableMapValue([]MapEntry{})
+		return newImmutableMap([]MapEntry{})
 	} else {
 		return sortedMapValue([]MapEntry{})
 	}
@@ -0,0 +1,158 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"github.com/pkg/errors"
+	"io"
+)
+
+/**
+	Incremental push parser for event-driven code
+
+	Accepts fragments of MessagePack stream of any size and returns complete top-level values
+	as soon as all their bytes arrived. Partial value is kept between calls and scanned only once.
+
+	Not safe for concurrent use.
+*/
+
+type Feeder struct {
+	buf      []byte
+	scanned  int   // bytes of the current value that already scanned
+	pending  int   // number of items still required to complete the current value
+	parser   *messageParser
+}
+
+func NewFeeder() *Feeder {
+	return &Feeder{parser: MessageParser()}
+}
+
+/**
+	Appends fragment to the internal buffer and returns all completed values
+
+	Returns values parsed before the error together with the error, the feeder must not be used after it
+*/
+
+func (f *Feeder) Feed(fragment []byte) ([]Value, error) {
+
+	f.buf = append(f.buf, fragment...)
+
+	var values []Value
+	consumed := 0
+
+	for {
+		n, err := f.scan(consumed)
+		if err != nil {
+			return values, err
+		}
+		if n == 0 {
+			break
+		}
+
+		unpacker := MessageUnpacker(f.buf[consumed:consumed+n], true)
+		value, err := Parse(unpacker, f.parser)
+		if err != nil {
+			return values, errors.Wrapf(err, "feed value at buffer offset %d", consumed)
+		}
+		values = append(values, value)
+		consumed += n
+	}
+
+	if consumed > 0 {
+		m := copy(f.buf, f.buf[consumed:])
+		f.buf = f.buf[:m]
+	}
+
+	return values, nil
+}
+
+/**
+	Number of bytes received but not yet returned as values
+*/
+
+func (f *Feeder) Buffered() int {
+	return len(f.buf)
+}
+
+/**
+	Drops partial value and all buffered bytes
+*/
+
+func (f *Feeder) Reset() {
+	f.buf = f.buf[:0]
+	f.scanned = 0
+	f.pending = 0
+	f.parser = MessageParser()
+}
+
+/**
+	Signals the end of input, returns io.ErrUnexpectedEOF if partial value left in the buffer
+*/
+
+func (f *Feeder) Close() error {
+	if len(f.buf) > 0 {
+		return errors.Wrapf(io.ErrUnexpectedEOF, "%d bytes of incomplete value", len(f.buf))
+	}
+	return nil
+}
+
+/**
+	Continues scanning of the value that starts at the offset in the buffer
+
+	Returns the size of the value when it is complete or zero if more bytes needed
+*/
+
+func (f *Feeder) scan(off int) (int, error) {
+
+	if f.pending == 0 {
+		f.pending = 1
+		f.scanned = 0
+	}
+
+	for f.pending > 0 {
+
+		rest := f.buf[off+f.scanned:]
+		if len(rest) == 0 {
+			return 0, nil
+		}
+
+		format, size := nextFormat(rest[0])
+		n := 1 + size
+		if len(rest) < n {
+			return 0, nil
+		}
+
+		header := rest[:n]
+		payload, items := 0, 0
+
+		switch format {
+		case BinHeader:
+			payload = f.parser.ParseBin(header)
+		case StrHeader:
+			payload = f.parser.ParseStr(header)
+		case ExtHeader:
+			len, _ := f.parser.ParseExt(header)
+			payload = len + 1
+		case ListHeader:
+			items = f.parser.ParseList(header)
+		case MapHeader:
+			items = 2 * f.parser.ParseMap(header)
+		}
+
+		if f.parser.Error() != nil {
+			return 0, errors.Wrapf(f.parser.Error(), "feed header at buffer offset %d", off+f.scanned)
+		}
+
+		if len(rest) < n+payload {
+			return 0, nil
+		}
+
+		f.scanned += n + payload
+		f.pending += items - 1
+	}
+
+	return f.scanned, nil
+}
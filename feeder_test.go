@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/stretchr/testify/require"
+)
+
+func testFeederStream(t *testing.T) ([]val.Value, []byte) {
+
+	values := []val.Value {
+		testCreateMap(),
+		val.Utf8("text"),
+		val.Long(-123456789),
+		val.Raw(bytes.Repeat([]byte{7}, 300), false),
+		val.ParseNumber("0x01e2afx-03"),
+		val.EmptyImmutableList(),
+	}
+
+	buf := bytes.Buffer{}
+	for _, v := range values {
+		require.Nil(t, val.Write(&buf, v))
+	}
+
+	return values, buf.Bytes()
+}
+
+func TestFeederByteByByte(t *testing.T) {
+
+	values, stream := testFeederStream(t)
+
+	f := val.NewFeeder()
+	var actual []val.Value
+
+	for i := range stream {
+		list, err := f.Feed(stream[i:i+1])
+		require.Nil(t, err)
+		actual = append(actual, list...)
+	}
+
+	require.Nil(t, f.Close())
+	require.Equal(t, len(values), len(actual))
+	for i, v := range values {
+		require.True(t, v.Equal(actual[i]))
+	}
+
+}
+
+func TestFeederChunks(t *testing.T) {
+
+	values, stream := testFeederStream(t)
+
+	for _, size := range []int{2, 3, 7, 64, len(stream)} {
+
+		f := val.NewFeeder()
+		var actual []val.Value
+
+		for i := 0; i < len(stream); i += size {
+			j := i + size
+			if j > len(stream) {
+				j = len(stream)
+			}
+			list, err := f.Feed(stream[i:j])
+			require.Nil(t, err)
+			actual = append(actual, list...)
+		}
+
+		require.Equal(t, 0, f.Buffered())
+		require.Equal(t, len(values), len(actual))
+		for i, v := range values {
+			require.True(t, v.Equal(actual[i]))
+		}
+	}
+
+}
+
+func TestFeederPartial(t *testing.T) {
+
+	mp, err := val.Pack(testCreateMap())
+	require.Nil(t, err)
+
+	f := val.NewFeeder()
+	list, err := f.Feed(mp[:len(mp)-1])
+	require.Nil(t, err)
+	require.Equal(t, 0, len(list))
+	require.Equal(t, len(mp)-1, f.Buffered())
+	require.True(t, errors.Is(f.Close(), io.ErrUnexpectedEOF))
+
+	f.Reset()
+	require.Equal(t, 0, f.Buffered())
+	require.Nil(t, f.Close())
+
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"github.com/pkg/errors"
+	"reflect"
+)
+
+/**
+	Struct fields tagged by `encrypt:"keyname"` are sealed by the key from the keyring passed to PackStructWith
+	and UnpackStructWith, the key name is the key id in the keyring
+
+	PackStructWith seals the packed field value into the envelope stored as EncryptedExt and fails if the key is missing,
+	values that are already EncryptedExt are packed as is.
+	UnpackStructWith decrypts the field if the key is available, otherwise the field gets the opaque UNKNOWN value
+	with EncryptedExt tag when its type can hold it, like value.Value, or fails with ErrUnknownKey, so the sealed
+	value is never dropped silently.
+*/
+
+func packEncryptedField(p Packer, val Value, field *Field, keyring Keyring) error {
+	if x, ok := val.(unknownValue); ok && len(x) > 0 && x.Tag() == EncryptedExt {
+		// opaque value left by UnpackStruct without the key is already sealed
+		x.Pack(p)
+		return nil
+	}
+	if keyring == nil {
+		return errors.Errorf("no keyring to encrypt field '%s'", field.FieldName)
+	}
+	key, err := keyring.Key(field.EncryptKey)
+	if err != nil {
+		return errors.Wrapf(err, "encrypt field '%s'", field.FieldName)
+	}
+	unencrypted, err := Pack(val)
+	if err != nil {
+		return err
+	}
+	envelope, err := sealEnvelope(unencrypted, DefaultEnvelopeAlg, field.EncryptKey, key)
+	if err != nil {
+		return err
+	}
+	p.PackExt(EncryptedExt, envelope)
+	return nil
+}
+
+/**
+	Parses value of the field, decrypts it for the encrypted fields
+
+	Returns ErrUnknownKey for the encrypted value that can not be decrypted and can not be assigned to the field
+*/
+
+func parseFieldElement(unpacker Unpacker, parser Parser, field *Field, keyring Keyring) (Value, error) {
+
+	val, err := doParseElement(unpacker, parser)
+	if err != nil || val == nil {
+		return val, err
+	}
+
+	x, ok := val.(unknownValue)
+	if !ok || len(x) == 0 || x.Tag() != EncryptedExt {
+		return val, nil
+	}
+
+	if keyring != nil {
+		decrypted, err := openEnvelope(x.Data(), keyring)
+		if err == nil {
+			return Unpack(decrypted, true)
+		}
+		if !errors.Is(err, ErrUnknownKey) {
+			return nil, errors.Wrapf(err, "decrypt field '%s'", field.FieldName)
+		}
+	}
+
+	elemType := field.FieldType
+	if field.Array {
+		elemType = elemType.Elem()
+	}
+	if reflect.TypeOf(x).AssignableTo(elemType) {
+		return x, nil
+	}
+	return nil, errors.Wrapf(ErrUnknownKey, "decrypt field '%s', type %v can not hold the encrypted value", field.FieldName, elemType)
+}
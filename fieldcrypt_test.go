@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"github.com/codeallergy/value"
+	"github.com/stretchr/testify/require"
+)
+
+type Customer struct {
+
+	Name      value.String    `tag:"1"`
+	Email     value.String    `tag:"2" encrypt:"pii"`
+	Token     value.Value     `tag:"3" encrypt:"pii"`
+	Phones    []value.String  `tag:"4" encrypt:"pii"`
+
+}
+
+func TestEncryptedFields(t *testing.T) {
+
+	keyring := value.NewKeyring()
+	require.NoError(t, keyring.Add("pii", randomKey()))
+
+	s := Customer{
+		Name: value.Utf8("Alice"),
+		Email: value.Utf8("alice@example.com"),
+		Token: value.Long(12345),
+		Phones: []value.String{ value.Utf8("555-0100"), value.Utf8("555-0101") },
+	}
+
+	blob, err := value.PackStructWith(&s, keyring)
+	require.NoError(t, err)
+
+	require.True(t, bytes.Contains(blob, []byte("Alice")))
+	require.False(t, bytes.Contains(blob, []byte("alice@example.com")))
+	require.False(t, bytes.Contains(blob, []byte("555-0100")))
+
+	// plain fields are queryable
+	obj, err := value.Unpack(blob, false)
+	require.NoError(t, err)
+	require.Equal(t, "Alice", obj.(value.List).GetAt(1).String())
+	require.Equal(t, value.UNKNOWN, obj.(value.List).GetAt(2).Kind())
+	require.True(t, strings.Contains(value.Dump(blob), `encrypted xchacha20-poly1305 key="pii"`))
+
+	var d Customer
+	require.NoError(t, value.UnpackStructWith(blob, &d, false, keyring))
+	require.True(t, s.Name.Equal(d.Name))
+	require.True(t, s.Email.Equal(d.Email))
+	require.True(t, s.Token.Equal(d.Token))
+	require.Equal(t, 2, len(d.Phones))
+	require.True(t, s.Phones[1].Equal(d.Phones[1]))
+
+	// without the key the typed field can not hold the opaque value
+	var e Customer
+	err = value.UnpackStructWith(blob, &e, false, value.NewKeyring())
+	require.True(t, errors.Is(err, value.ErrUnknownKey), "%v", err)
+	require.True(t, strings.Contains(err.Error(), "Email"))
+
+	err = value.UnpackStruct(blob, &e, false)
+	require.True(t, errors.Is(err, value.ErrUnknownKey), "%v", err)
+
+	// without the key the value.Value field stays opaque
+	var o struct {
+		Name    value.String  `tag:"1"`
+		Email   value.Value   `tag:"2" encrypt:"pii"`
+		Token   value.Value   `tag:"3" encrypt:"pii"`
+		Phones  []value.Value `tag:"4" encrypt:"pii"`
+	}
+	require.NoError(t, value.UnpackStructWith(blob, &o, false, value.NewKeyring()))
+	require.True(t, s.Name.Equal(o.Name))
+	require.Equal(t, value.UNKNOWN, o.Email.Kind())
+	require.Equal(t, value.UNKNOWN, o.Token.Kind())
+	require.Equal(t, 2, len(o.Phones))
+	require.Equal(t, value.UNKNOWN, o.Phones[0].Kind())
+
+	// opaque value is packed back as is
+	again, err := value.Pack(o.Token)
+	require.NoError(t, err)
+	require.True(t, bytes.Contains(blob, again))
+
+	// missing key on pack is an error, plaintext is never written
+	_, err = value.PackStruct(&s)
+	require.Error(t, err)
+
+	_, err = value.PackStructWith(&s, value.NewKeyring())
+	require.True(t, errors.Is(err, value.ErrUnknownKey), "%v", err)
+
+}
+
+type Account struct {
+
+	Id      value.Number  `tag:"1"`
+	Secret  value.Value   `tag:"2" encrypt:"pii"`
+
+}
+
+func TestEncryptedFieldRepack(t *testing.T) {
+
+	keyring := value.NewKeyring()
+	require.NoError(t, keyring.Add("pii", randomKey()))
+
+	s := Account{ Id: value.Long(7), Secret: value.Utf8("s3cr3t") }
+
+	blob, err := value.PackStructWith(&s, keyring)
+	require.NoError(t, err)
+
+	// service without the key passes the sealed field through
+	var d Account
+	require.NoError(t, value.UnpackStruct(blob, &d, true))
+	require.Equal(t, value.UNKNOWN, d.Secret.Kind())
+	d.Id = value.Long(8)
+
+	repacked, err := value.PackStruct(&d)
+	require.NoError(t, err)
+
+	// service with the key does not encrypt the sealed field twice
+	var e Account
+	require.NoError(t, value.UnpackStructWith(repacked, &e, false, keyring))
+	require.Equal(t, int64(8), e.Id.Long())
+	require.Equal(t, value.STRING, e.Secret.Kind())
+	require.Equal(t, "s3cr3t", e.Secret.String())
+
+	opaque := Account{ Id: value.Long(9) }
+	require.NoError(t, value.UnpackStruct(blob, &opaque, true))
+	again, err := value.PackStructWith(&opaque, keyring)
+	require.NoError(t, err)
+
+	var f Account
+	require.NoError(t, value.UnpackStructWith(again, &f, false, keyring))
+	require.Equal(t, "s3cr3t", f.Secret.String())
+
+}
+
+func TestEncryptedFieldsConcurrent(t *testing.T) {
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// every goroutine has own keyring, no shared state between them
+			keyring := value.NewKeyring()
+			require.NoError(t, keyring.Add("pii", randomKey()))
+
+			s := Account{ Id: value.Long(int64(i)), Secret: value.Long(int64(i * 10)) }
+			blob, err := value.PackStructWith(&s, keyring)
+			require.NoError(t, err)
+
+			var d Account
+			require.NoError(t, value.UnpackStructWith(blob, &d, false, keyring))
+			require.Equal(t, int64(i * 10), d.Secret.(value.Number).Long())
+		}(i)
+	}
+	wg.Wait()
+
+}
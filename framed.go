@@ -0,0 +1,431 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"hash/crc32"
+	"io"
+)
+
+/**
+	Framed stream format
+
+	[magic "VALF"][version]   optional stream header
+	[uvarint length][payload][crc32c]   record per value
+
+	Checksum is Castagnoli CRC32 in big endian over the length prefix and payload,
+	payload is exactly one packed value
+*/
+
+const (
+	FramedVersion  byte = 1
+
+	framedHeaderSize    = 5
+	framedChecksumSize  = 4
+	framedReadChunk     = 4096
+	framedCheckpoint    = 4096
+)
+
+var FramedMagic = []byte("VALF")
+
+/**
+	Limits the payload size accepted by FramedReader, protects from corrupted length prefixes
+*/
+
+var MaxFrameSize = 64 * 1024 * 1024
+
+var ErrCorruptFrame = errors.New("corrupt frame")
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+type FramedWriter struct {
+	w            io.Writer
+	header       bool
+	buf          bytes.Buffer
+	err          error
+}
+
+/**
+	Creates writer of framed stream, the header is written together with the first frame
+*/
+
+func NewFramedWriter(w io.Writer, header bool) *FramedWriter {
+	return &FramedWriter{w: w, header: header}
+}
+
+/**
+	Writes value as a single frame, every frame goes to the underlying writer by one call
+*/
+
+func (fw *FramedWriter) Write(val Value) error {
+
+	if fw.err != nil {
+		return fw.err
+	}
+
+	fw.buf.Reset()
+	if fw.header {
+		fw.buf.Write(FramedMagic)
+		fw.buf.WriteByte(FramedVersion)
+	}
+
+	var prefix [binary.MaxVarintLen64]byte
+	payload, err := Pack(val)
+	if err != nil {
+		return err
+	}
+	n := binary.PutUvarint(prefix[:], uint64(len(payload)))
+
+	crc := crc32.Update(0, crc32c, prefix[:n])
+	crc = crc32.Update(crc, crc32c, payload)
+
+	var checksum [framedChecksumSize]byte
+	binary.BigEndian.PutUint32(checksum[:], crc)
+
+	fw.buf.Write(prefix[:n])
+	fw.buf.Write(payload)
+	fw.buf.Write(checksum[:])
+
+	if _, fw.err = fw.w.Write(fw.buf.Bytes()); fw.err != nil {
+		return fw.err
+	}
+
+	fw.header = false
+	return nil
+}
+
+type FramedReader struct {
+	r            io.Reader
+	header       bool
+	buf          []byte
+	pos          int
+	off          int64  // offset of pos in the stream
+	err          error  // sticky error of the underlying reader
+	resync       *crcIndex
+}
+
+/**
+	Creates reader of framed stream, header flag must match the writer
+*/
+
+func NewFramedReader(r io.Reader, header bool) *FramedReader {
+	return &FramedReader{r: r, header: header}
+}
+
+/**
+	Offset of the next frame in the stream
+*/
+
+func (fr *FramedReader) Offset() int64 {
+	return fr.off
+}
+
+/**
+	Reads next value
+
+	Returns io.EOF on the clean end of stream, io.ErrUnexpectedEOF on truncated frame
+	and ErrCorruptFrame on checksum mismatch, the corrupted frame is not skipped, use Resync for that
+*/
+
+func (fr *FramedReader) Read() (Value, error) {
+
+	if fr.header {
+		if err := fr.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+
+	value, size, err := fr.frame()
+	if err != nil {
+		return nil, err
+	}
+
+	fr.consume(size)
+	return value, nil
+}
+
+/**
+	Skips at least one byte and continues until the beginning of the next valid frame
+
+	Returns number of skipped bytes, io.EOF if no valid frames left in the stream
+*/
+
+func (fr *FramedReader) Resync() (int64, error) {
+
+	var skipped int64
+
+	// checksums of the candidate frames are derived from the running checksum, so every
+	// candidate costs O(framedCheckpoint) even if its corrupted length points far ahead
+	fr.resync = &crcIndex{start: fr.off, at: fr.off, frontAt: fr.off, checkpoints: []uint32{0}}
+	defer func() {
+		fr.resync = nil
+	}()
+
+	for {
+		if err := fr.fill(1); err != nil {
+			return skipped, err
+		}
+		fr.resync.skip(fr, 1)
+		fr.consume(1)
+		skipped++
+
+		_, _, err := fr.frame()
+		if err == nil {
+			return skipped, nil
+		}
+		if err == io.EOF {
+			return skipped, err
+		}
+		if !errors.Is(err, ErrCorruptFrame) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return skipped, err
+		}
+	}
+}
+
+func (fr *FramedReader) readHeader() error {
+
+	if err := fr.fill(framedHeaderSize); err != nil {
+		if err == io.EOF && fr.available() > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	header := fr.buf[fr.pos:fr.pos+framedHeaderSize]
+	if !bytes.Equal(header[:len(FramedMagic)], FramedMagic) {
+		return errors.Wrapf(ErrCorruptFrame, "invalid stream header %x", header)
+	}
+	if version := header[len(FramedMagic)]; version != FramedVersion {
+		return errors.Errorf("unsupported framed stream version %d", version)
+	}
+
+	fr.consume(framedHeaderSize)
+	fr.header = false
+	return nil
+}
+
+/**
+	Parses frame at the current position without consuming it
+*/
+
+func (fr *FramedReader) frame() (Value, int, error) {
+
+	if err := fr.fill(1); err != nil {
+		return nil, 0, err
+	}
+
+	// length prefix could be shorter than max varint at the end of stream
+	if err := fr.fill(binary.MaxVarintLen64); err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+
+	size, n := binary.Uvarint(fr.buf[fr.pos:])
+	if n == 0 {
+		return nil, 0, errors.Wrapf(io.ErrUnexpectedEOF, "frame length at offset %d", fr.off)
+	}
+	if n < 0 || size > uint64(MaxFrameSize) {
+		return nil, 0, errors.Wrapf(ErrCorruptFrame, "invalid frame length at offset %d", fr.off)
+	}
+
+	total := n + int(size) + framedChecksumSize
+	if err := fr.fill(total); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 0, errors.Wrapf(err, "frame of %d bytes at offset %d", total, fr.off)
+	}
+
+	frame := fr.buf[fr.pos:fr.pos+total]
+	payload := frame[n:n+int(size)]
+
+	var crc uint32
+	if fr.resync != nil {
+		crc = fr.resync.checksum(fr, int64(n) + int64(size))
+	} else {
+		crc = crc32.Checksum(frame[:n+int(size)], crc32c)
+	}
+	if crc != binary.BigEndian.Uint32(frame[n+int(size):]) {
+		return nil, 0, errors.Wrapf(ErrCorruptFrame, "checksum mismatch at offset %d", fr.off)
+	}
+
+	unpacker := MessageUnpacker(payload, true)
+	value, err := Parse(unpacker, MessageParser())
+	if err != nil || unpacker.Offset() != int64(len(payload)) {
+		return nil, 0, errors.Wrapf(ErrCorruptFrame, "invalid payload at offset %d", fr.off)
+	}
+
+	return value, total, nil
+}
+
+/**
+	Buffered bytes between stream offsets, must be at or after the current position
+*/
+
+func (fr *FramedReader) bytesAt(from, to int64) []byte {
+	return fr.buf[fr.pos + int(from - fr.off):fr.pos + int(to - fr.off)]
+}
+
+func (fr *FramedReader) available() int {
+	return len(fr.buf) - fr.pos
+}
+
+func (fr *FramedReader) consume(n int) {
+	fr.pos += n
+	fr.off += int64(n)
+}
+
+/**
+	Makes at least n bytes available in the buffer, returns io.EOF if the stream ended before
+*/
+
+func (fr *FramedReader) fill(n int) error {
+
+	for fr.available() < n {
+
+		if fr.err != nil {
+			return fr.err
+		}
+
+		if fr.pos > 0 {
+			m := copy(fr.buf, fr.buf[fr.pos:])
+			fr.buf = fr.buf[:m]
+			fr.pos = 0
+		}
+
+		if need := n + framedReadChunk; cap(fr.buf) < need {
+			dst := make([]byte, len(fr.buf), need)
+			copy(dst, fr.buf)
+			fr.buf = dst
+		}
+
+		m, err := fr.r.Read(fr.buf[len(fr.buf):cap(fr.buf)])
+		fr.buf = fr.buf[:len(fr.buf)+m]
+		if err != nil {
+			fr.err = err
+		}
+	}
+
+	return nil
+}
+
+/**
+	Running checksums of the stream from the start of Resync
+
+	Checksum of any range is derived from the checksums of two prefixes by the CRC combine operation,
+	prefixes are computed once and kept every framedCheckpoint bytes
+*/
+
+type crcIndex struct {
+	start        int64
+	at           int64     // current position, crc is the checksum of [start, at)
+	crc          uint32
+	frontAt      int64     // furthest computed position, front is the checksum of [start, frontAt)
+	front        uint32
+	checkpoints  []uint32  // checksums of [start, start + i*framedCheckpoint)
+}
+
+func (c *crcIndex) skip(fr *FramedReader, n int64) {
+	c.advance(fr, c.at + n)
+	c.crc = crc32.Update(c.crc, crc32c, fr.bytesAt(c.at, c.at + n))
+	c.at += n
+}
+
+func (c *crcIndex) advance(fr *FramedReader, to int64) {
+	for c.frontAt < to {
+		next := c.start + int64(len(c.checkpoints)) * framedCheckpoint
+		end := to
+		if next < end {
+			end = next
+		}
+		c.front = crc32.Update(c.front, crc32c, fr.bytesAt(c.frontAt, end))
+		c.frontAt = end
+		if end == next {
+			c.checkpoints = append(c.checkpoints, c.front)
+		}
+	}
+}
+
+/**
+	Checksum of [start, to), the bytes up to the position must be buffered
+*/
+
+func (c *crcIndex) prefix(fr *FramedReader, to int64) uint32 {
+	if to >= c.frontAt {
+		c.advance(fr, to)
+		return c.front
+	}
+	if to - c.at < framedCheckpoint {
+		return crc32.Update(c.crc, crc32c, fr.bytesAt(c.at, to))
+	}
+	k := (to - c.start) / framedCheckpoint
+	return crc32.Update(c.checkpoints[k], crc32c, fr.bytesAt(c.start + k * framedCheckpoint, to))
+}
+
+/**
+	Checksum of n bytes at the current position
+*/
+
+func (c *crcIndex) checksum(fr *FramedReader, n int64) uint32 {
+	return c.prefix(fr, c.at + n) ^ crc32Shift(c.crc, n)
+}
+
+/**
+	Appends n zero bytes to the raw crc register, crc(a + b) = crc32Shift(crc(a), len(b)) ^ crc(b), same as crc32_combine of zlib
+*/
+
+func crc32Shift(crc uint32, n int64) uint32 {
+
+	var odd, even [32]uint32
+
+	// operator for one zero bit
+	odd[0] = crc32.Castagnoli
+	row := uint32(1)
+	for i := 1; i < 32; i++ {
+		odd[i] = row
+		row <<= 1
+	}
+
+	gf2MatrixSquare(&even, &odd)  // two zero bits
+	gf2MatrixSquare(&odd, &even)  // four zero bits
+
+	for n > 0 {
+		gf2MatrixSquare(&even, &odd)
+		if n & 1 != 0 {
+			crc = gf2MatrixTimes(&even, crc)
+		}
+		n >>= 1
+		if n == 0 {
+			break
+		}
+		gf2MatrixSquare(&odd, &even)
+		if n & 1 != 0 {
+			crc = gf2MatrixTimes(&odd, crc)
+		}
+		n >>= 1
+	}
+
+	return crc
+}
+
+func gf2MatrixTimes(mat *[32]uint32, vec uint32) uint32 {
+	var sum uint32
+	for i := 0; vec != 0; i++ {
+		if vec & 1 != 0 {
+			sum ^= mat[i]
+		}
+		vec >>= 1
+	}
+	return sum
+}
+
+func gf2MatrixSquare(square, mat *[32]uint32) {
+	for i := 0; i < 32; i++ {
+		square[i] = gf2MatrixTimes(mat, mat[i])
+	}
+}
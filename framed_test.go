@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	val "github.com/codeallergy/value"
+	"github.com/stretchr/testify/require"
+)
+
+func testFramedStream(t *testing.T, header bool) ([]val.Value, []byte) {
+
+	values := []val.Value {
+		testCreateMap(),
+		val.Utf8("text"),
+		val.Long(-123456789),
+		val.Raw(bytes.Repeat([]byte{7}, 300), false),
+	}
+
+	buf := bytes.Buffer{}
+	w := val.NewFramedWriter(&buf, header)
+	for _, v := range values {
+		require.Nil(t, w.Write(v))
+	}
+
+	return values, buf.Bytes()
+}
+
+func TestFramedStream(t *testing.T) {
+
+	for _, header := range []bool{false, true} {
+
+		values, stream := testFramedStream(t, header)
+		if header {
+			require.Equal(t, []byte("VALF\x01"), stream[:5])
+		}
+
+		r := val.NewFramedReader(iotest.HalfReader(bytes.NewReader(stream)), header)
+		for _, v := range values {
+			actual, err := r.Read()
+			require.Nil(t, err)
+			require.True(t, v.Equal(actual))
+		}
+
+		_, err := r.Read()
+		require.Equal(t, io.EOF, err)
+		require.Equal(t, int64(len(stream)), r.Offset())
+	}
+
+}
+
+func TestFramedCorruption(t *testing.T) {
+
+	values, stream := testFramedStream(t, true)
+
+	first, err := val.Pack(values[0])
+	require.Nil(t, err)
+
+	var prefix [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(prefix[:], uint64(len(first)))
+
+	// damage payload of the first frame
+	corrupted := append([]byte{}, stream...)
+	corrupted[5 + n + len(first) / 2] ^= 0xff
+
+	r := val.NewFramedReader(bytes.NewReader(corrupted), true)
+
+	_, err = r.Read()
+	require.True(t, errors.Is(err, val.ErrCorruptFrame))
+
+	skipped, err := r.Resync()
+	require.Nil(t, err)
+	require.Equal(t, int64(n + len(first) + 4), skipped)
+
+	for _, v := range values[1:] {
+		actual, err := r.Read()
+		require.Nil(t, err)
+		require.True(t, v.Equal(actual))
+	}
+
+	_, err = r.Resync()
+	require.Equal(t, io.EOF, err)
+
+}
+
+func TestFramedTruncated(t *testing.T) {
+
+	_, stream := testFramedStream(t, false)
+
+	r := val.NewFramedReader(bytes.NewReader(stream[:len(stream)-1]), false)
+
+	var err error
+	for err == nil {
+		_, err = r.Read()
+	}
+	require.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+	r = val.NewFramedReader(bytes.NewReader([]byte("VAL")), true)
+	_, err = r.Read()
+	require.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+	r = val.NewFramedReader(bytes.NewReader([]byte("XXXX\x01")), true)
+	_, err = r.Read()
+	require.True(t, errors.Is(err, val.ErrCorruptFrame))
+
+}
+
+func TestFramedResyncFarLength(t *testing.T) {
+
+	big := val.Raw(bytes.Repeat([]byte{1, 2, 3}, 2 << 20), false)
+
+	buf := bytes.Buffer{}
+	buf.Write([]byte("VALF\x01"))
+	// length prefixes of 4 MiB, 32 KiB, 256 and 2 bytes point into the next frame
+	damage := bytes.Repeat([]byte{0x80, 0x80, 0x80, 0x02}, 1000)
+	buf.Write(damage)
+	w := val.NewFramedWriter(&buf, false)
+	require.Nil(t, w.Write(big))
+	require.Nil(t, w.Write(val.Long(7)))
+
+	r := val.NewFramedReader(iotest.HalfReader(bytes.NewReader(buf.Bytes())), true)
+
+	_, err := r.Read()
+	require.True(t, errors.Is(err, val.ErrCorruptFrame))
+
+	skipped, err := r.Resync()
+	require.Nil(t, err)
+	require.Equal(t, int64(len(damage)), skipped)
+
+	actual, err := r.Read()
+	require.Nil(t, err)
+	require.True(t, big.Equal(actual))
+
+	actual, err = r.Read()
+	require.Nil(t, err)
+	require.Equal(t, int64(7), actual.(val.Number).Long())
+
+	_, err = r.Read()
+	require.Equal(t, io.EOF, err)
+
+}
@@ -0,0 +1,301 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+type hashCoder interface {
+	hashCode() uint64
+}
+
+func (t immutableListValue) immutableChildren() bool {
+	for _, el := range t.list {
+		if !isImmutable(el) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t immutableMapValue) immutableChildren() bool {
+	for _, entry := range t.list {
+		if _, ok := entry.(*immutableMapEntry); !ok || !isImmutable(entry.Value()) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t immutableListValue) hashCode() uint64 {
+	m := t.memo.load()
+	if m == nil || !m.immutable(t.immutableChildren) {
+		return computeHashCode(t)
+	}
+	m.hashOnce.Do(func() {
+		m.hash = computeHashCode(t)
+	})
+	return m.hash
+}
+
+func (t immutableMapValue) hashCode() uint64 {
+	m := t.memo.load()
+	if m == nil || !m.immutable(t.immutableChildren) {
+		return computeHashCode(t)
+	}
+	m.hashOnce.Do(func() {
+		m.hash = computeHashCode(t)
+	})
+	return m.hash
+}
+
+/**
+	Returns 64-bit hash of the value consistent with Equal: equal values have equal hash codes
+
+	Numbers are hashed in the canonical form, so Long(1) and Double(1.0) have the same hash code,
+	strings are hashed by bytes regardless of the type. Hash codes of immutable containers are cached
+	if they have no mutable containers inside.
+*/
+
+func HashCode(val Value) uint64 {
+	if val != nil {
+		if c, ok := val.(hashCoder); ok {
+			return c.hashCode()
+		}
+	}
+	return computeHashCode(val)
+}
+
+func computeHashCode(val Value) uint64 {
+
+	h := fnv.New64a()
+	var buf [8]byte
+
+	writeUint64 := func(v uint64) {
+		binary.BigEndian.PutUint64(buf[:], v)
+		h.Write(buf[:])
+	}
+
+	kind := kindOf(val)
+	h.Write([]byte{ byte(kind) })
+
+	switch kind {
+
+	case BOOL:
+		writeUint64(uint64(boolToLong(val.(Bool).Boolean())))
+
+	case NUMBER:
+		n := Canonical(val.(Number))
+		h.Write([]byte{ byte(n.Type()) })
+		switch n.Type() {
+		case LONG:
+			writeUint64(uint64(n.Long()))
+		case DOUBLE:
+			writeUint64(math.Float64bits(n.Double()))
+		case BIGINT:
+			i := n.BigInt()
+			writeUint64(uint64(i.Sign()))
+			h.Write(i.Bytes())
+		default:
+			d := n.Decimal()
+			writeUint64(uint64(d.Exponent()))
+			writeUint64(uint64(d.Coefficient().Sign()))
+			h.Write(d.Coefficient().Bytes())
+		}
+
+	case STRING:
+		h.Write(val.(String).Raw())
+
+	case LIST:
+		values := val.(List).Values()
+		writeUint64(uint64(len(values)))
+		for _, el := range values {
+			writeUint64(HashCode(el))
+		}
+
+	case MAP:
+		keys, values := mapPairs(val.(Map))
+		writeUint64(uint64(len(keys)))
+		for i, key := range keys {
+			writeUint64(HashCode(key))
+			writeUint64(HashCode(values[i]))
+		}
+
+	case UNKNOWN:
+		h.Write(nativeOf(val))
+
+	}
+
+	return h.Sum64()
+}
+
+/**
+	Set of values with hash based lookup, values are compared by Equal
+
+	NaN is never equal to itself, so every added NaN is a new element. Not thread-safe.
+*/
+
+type ValueSet struct {
+	buckets  map[uint64][]Value
+	size     int
+}
+
+func NewValueSet(values ...Value) *ValueSet {
+	s := &ValueSet{buckets: make(map[uint64][]Value)}
+	for _, val := range values {
+		s.Add(val)
+	}
+	return s
+}
+
+/**
+	Adds value to the set, returns false if the equal value is already there
+*/
+
+func (s *ValueSet) Add(val Value) bool {
+	code := HashCode(val)
+	for _, el := range s.buckets[code] {
+		if Equal(el, val) {
+			return false
+		}
+	}
+	s.buckets[code] = append(s.buckets[code], val)
+	s.size++
+	return true
+}
+
+func (s *ValueSet) Contains(val Value) bool {
+	for _, el := range s.buckets[HashCode(val)] {
+		if Equal(el, val) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+	Removes value from the set, returns false if there is no equal value
+*/
+
+func (s *ValueSet) Remove(val Value) bool {
+	code := HashCode(val)
+	bucket := s.buckets[code]
+	for i, el := range bucket {
+		if Equal(el, val) {
+			if len(bucket) == 1 {
+				delete(s.buckets, code)
+			} else {
+				s.buckets[code] = append(bucket[:i:i], bucket[i+1:]...)
+			}
+			s.size--
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ValueSet) Len() int {
+	return s.size
+}
+
+/**
+	Values of the set in the undefined order
+*/
+
+func (s *ValueSet) Values() []Value {
+	values := make([]Value, 0, s.size)
+	for _, bucket := range s.buckets {
+		values = append(values, bucket...)
+	}
+	return values
+}
+
+/**
+	Hash map with value keys, keys are compared by Equal. Not thread-safe.
+*/
+
+type ValueIndex struct {
+	buckets  map[uint64][]indexEntry
+	size     int
+}
+
+type indexEntry struct {
+	key    Value
+	value  Value
+}
+
+func NewValueIndex() *ValueIndex {
+	return &ValueIndex{buckets: make(map[uint64][]indexEntry)}
+}
+
+/**
+	Puts value by the key, returns the previous value or nil
+*/
+
+func (t *ValueIndex) Put(key, val Value) Value {
+	code := HashCode(key)
+	bucket := t.buckets[code]
+	for i, e := range bucket {
+		if Equal(e.key, key) {
+			bucket[i].value = val
+			return e.value
+		}
+	}
+	t.buckets[code] = append(bucket, indexEntry{key, val})
+	t.size++
+	return nil
+}
+
+func (t *ValueIndex) Get(key Value) (Value, bool) {
+	for _, e := range t.buckets[HashCode(key)] {
+		if Equal(e.key, key) {
+			return e.value, true
+		}
+	}
+	return nil, false
+}
+
+/**
+	Removes the key, returns the removed value or nil
+*/
+
+func (t *ValueIndex) Remove(key Value) Value {
+	code := HashCode(key)
+	bucket := t.buckets[code]
+	for i, e := range bucket {
+		if Equal(e.key, key) {
+			if len(bucket) == 1 {
+				delete(t.buckets, code)
+			} else {
+				t.buckets[code] = append(bucket[:i:i], bucket[i+1:]...)
+			}
+			t.size--
+			return e.value
+		}
+	}
+	return nil
+}
+
+func (t *ValueIndex) Len() int {
+	return t.size
+}
+
+/**
+	Calls the function for every key and value in the undefined order until it returns false
+*/
+
+func (t *ValueIndex) Range(fn func(key, val Value) bool) {
+	for _, bucket := range t.buckets {
+		for _, e := range bucket {
+			if !fn(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
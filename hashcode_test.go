@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"github.com/shopspring/decimal"
+	"math/big"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashCode(t *testing.T) {
+
+	equal := [][]val.Value {
+		{ val.Long(1), val.Double(1), val.BigInt(big.NewInt(1)), val.Decimal(decimal.RequireFromString("1.00")) },
+		{ val.Double(0.5), val.Decimal(decimal.RequireFromString("0.50")) },
+		{ val.Utf8("abc"), val.Raw([]byte("abc"), false) },
+		{ val.Tuple(val.Long(1), val.Utf8("a")), val.ImmutableList([]val.Value{ val.Double(1), val.Utf8("a") }) },
+		{ val.ImmutableMapOf(map[string]val.Value{ "a": val.Long(1) }), val.EmptyMutableMap().Put("a", val.Double(1)) },
+		{ nil, val.Null },
+	}
+
+	for _, group := range equal {
+		for _, a := range group {
+			for _, b := range group {
+				require.Equal(t, val.HashCode(a), val.HashCode(b), "%v %v", a, b)
+			}
+		}
+	}
+
+	require.NotEqual(t, val.HashCode(val.Long(1)), val.HashCode(val.Long(2)))
+	require.NotEqual(t, val.HashCode(val.Long(1)), val.HashCode(val.Utf8("1")))
+	require.NotEqual(t, val.HashCode(val.Tuple(val.Long(1), val.Long(2))), val.HashCode(val.Tuple(val.Long(2), val.Long(1))))
+	require.NotEqual(t, val.HashCode(val.True), val.HashCode(val.False))
+
+	list := val.ImmutableList([]val.Value{ val.Long(1) })
+	require.Equal(t, val.HashCode(list), val.HashCode(list))
+	require.NotEqual(t, val.HashCode(list), val.HashCode(list.Append(val.Long(2))))
+
+}
+
+func TestValueSet(t *testing.T) {
+
+	s := val.NewValueSet(val.Long(1), val.Double(1), val.Utf8("a"), val.Tuple(val.Long(1)))
+	require.Equal(t, 3, s.Len())
+
+	require.True(t, s.Contains(val.Decimal(decimal.RequireFromString("1.0"))))
+	require.True(t, s.Contains(val.ImmutableList([]val.Value{ val.Long(1) })))
+	require.False(t, s.Contains(val.Utf8("b")))
+
+	require.False(t, s.Add(val.Utf8("a")))
+	require.True(t, s.Add(val.Utf8("b")))
+	require.Equal(t, 4, s.Len())
+
+	require.True(t, s.Remove(val.Long(1)))
+	require.False(t, s.Remove(val.Long(1)))
+	require.Equal(t, 3, s.Len())
+	require.Equal(t, 3, len(s.Values()))
+
+}
+
+func TestValueIndex(t *testing.T) {
+
+	idx := val.NewValueIndex()
+	require.Nil(t, idx.Put(val.Long(1), val.Utf8("one")))
+	require.Nil(t, idx.Put(val.Tuple(val.Utf8("a"), val.Long(2)), val.Utf8("pair")))
+
+	v, ok := idx.Get(val.Double(1))
+	require.True(t, ok)
+	require.Equal(t, "one", v.String())
+
+	v, ok = idx.Get(val.ImmutableList([]val.Value{ val.Utf8("a"), val.Long(2) }))
+	require.True(t, ok)
+	require.Equal(t, "pair", v.String())
+
+	old := idx.Put(val.Long(1), val.Utf8("uno"))
+	require.Equal(t, "one", old.String())
+	require.Equal(t, 2, idx.Len())
+
+	cnt := 0
+	idx.Range(func(key, value val.Value) bool {
+		cnt++
+		return true
+	})
+	require.Equal(t, 2, cnt)
+
+	require.Equal(t, "uno", idx.Remove(val.Long(1)).String())
+	require.Nil(t, idx.Remove(val.Long(1)))
+	_, ok = idx.Get(val.Long(1))
+	require.False(t, ok)
+	require.Equal(t, 1, idx.Len())
+
+}
+
+type setValue struct {
+	val.Value
+}
+
+func (u setValue) Update(val.Value) val.Value {
+	return u.Value
+}
+
+func TestHashCodeMutableChild(t *testing.T) {
+
+	inner := val.EmptyMap(false).Put("a", val.Long(1))
+	outer := val.ImmutableList([]val.Value{ inner })
+	before := val.HashCode(outer)
+
+	require.True(t, inner.Update("a", setValue{val.Long(2)}))
+
+	fresh := val.ImmutableList([]val.Value{ val.EmptyMap(false).Put("a", val.Long(2)) })
+	require.True(t, outer.Equal(fresh))
+	require.NotEqual(t, before, val.HashCode(outer))
+	require.Equal(t, val.HashCode(fresh), val.HashCode(outer))
+
+	set := val.NewValueSet()
+	set.Add(outer)
+	require.True(t, set.Contains(fresh))
+
+	// mutable entries of the sorted map are shared with the immutable copy
+	src := val.EmptyMap(false).Put("a", val.Long(1))
+	copied := val.ImmutableMapCopyOf(src)
+	before = val.HashCode(copied)
+	require.True(t, src.Update("a", setValue{val.Long(2)}))
+	require.NotEqual(t, before, val.HashCode(copied))
+
+}
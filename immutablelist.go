@@ -7,6 +7,7 @@ package value
 
 import (
 	"bytes"
+	"crypto"
 	"reflect"
 	"strconv"
 	"strings"
@@ -17,16 +18,23 @@ type immutableListEntry struct {
 	value  Value
 }
 
-type immutableListValue []Value
+type immutableListValue struct {
+	list  []Value
+	memo  *memoRef
+}
+
+func newImmutableList(list []Value) immutableListValue {
+	return immutableListValue{list: list, memo: newMemoRef()}
+}
 
 var immutableListValueClass = reflect.TypeOf((*immutableListValue)(nil)).Elem()
 
 func EmptyImmutableList() List {
-	return immutableListValue([]Value{})
+	return newImmutableList([]Value{})
 }
 
 func ImmutableList(list []Value) List {
-	return immutableListValue(list)
+	return newImmutableList(list)
 }
 
 func (t immutableListValue) Kind() Kind {
@@ -38,7 +46,7 @@ func (t immutableListValue) Class() reflect.Type {
 }
 
 func (t immutableListValue) Object() interface{} {
-	return []Value(t)
+	return t.list
 }
 
 func (t immutableListValue) String() string {
@@ -49,7 +57,7 @@ func (t immutableListValue) String() string {
 
 func (t immutableListValue) Items() []ListItem {
 	var items []ListItem
-	for key, value := range t {
+	for key, value := range t.list {
 		items = append(items, ImmutableItem(key, value))
 	}
 	return items
@@ -57,25 +65,29 @@ func (t immutableListValue) Items() []ListItem {
 
 func (t immutableListValue) Entries() []MapEntry {
 	var entries []MapEntry
-	for key, value := range t {
+	for key, value := range t.list {
 		entries = append(entries, ImmutableEntry(strconv.Itoa(key), value))
 	}
 	return entries
 }
 
 func (t immutableListValue) Values() []Value {
-	return t
+	return t.list
 }
 
 func (t immutableListValue) Len() int {
-	return len(t)
+	return len(t.list)
 }
 
 func (t immutableListValue) Pack(p Packer) {
+	t.memo.pack(p, t.immutableChildren, t.pack)
+}
+
+func (t immutableListValue) pack(p Packer) {
 
-	p.PackList(len(t))
+	p.PackList(len(t.list))
 
-	for _, e := range t {
+	for _, e := range t.list {
 		if e != nil {
 			e.Pack(p)
 		} else {
@@ -86,7 +98,7 @@ func (t immutableListValue) Pack(p Packer) {
 
 func (t immutableListValue) PrintJSON(out *strings.Builder) {
 	out.WriteRune('[')
-	for i, e := range t {
+	for i, e := range t.list {
 		if i != 0 {
 			out.WriteRune(',')
 		}
@@ -120,7 +132,7 @@ func (t immutableListValue) Equal(val Value) bool {
 	if t.Len() != o.Len() {
 		return false
 	}
-	for i, item := range t {
+	for i, item := range t.list {
 		if !Equal(item, o.GetAt(i)) {
 			return false
 		}
@@ -129,8 +141,8 @@ func (t immutableListValue) Equal(val Value) bool {
 }
 
 func (t immutableListValue) GetAt(i int) Value {
-	if i >= 0 && i < len(t) {
-		return t[i]
+	if i >= 0 && i < len(t.list) {
+		return t.list[i]
 	}
 	return Null
 }
@@ -198,14 +210,14 @@ func (t immutableListValue) Append(val Value) List {
 	if val == nil {
 		val = Null
 	}
-	return t.append(len(t), val)
+	return t.append(len(t.list), val)
 }
 
 func (t immutableListValue) PutAt(i int, val Value) List {
 	if val == nil {
 		val = Null
 	}
-	n := len(t)
+	n := len(t.list)
 	if i >= 0 {
 		if i == n {
 			return t.append(n, val)
@@ -225,7 +237,7 @@ func (t immutableListValue) InsertAt(i int, val Value) List {
 		val = Null
 	}
 	if i >= 0 {
-		n := len(t)
+		n := len(t.list)
 		if i < n {
 			return t.insertAt(i, n, val)
 		} else {
@@ -236,7 +248,7 @@ func (t immutableListValue) InsertAt(i int, val Value) List {
 }
 
 func (t immutableListValue) RemoveAt(i int) List {
-	n := len(t)
+	n := len(t.list)
 	if i >= 0 && i < n {
 		return t.removeAt(i, n)
 	}
@@ -245,12 +257,12 @@ func (t immutableListValue) RemoveAt(i int) List {
 
 func (t immutableListValue) append(n int, val Value) List {
 	if n == 0 {
-		return immutableListValue([]Value{val})
+		return newImmutableList([]Value{val})
 	} else {
 		dst := make([]Value, n+1)
-		copy(dst, t)
+		copy(dst, t.list)
 		dst[n] = val
-		return immutableListValue(dst)
+		return newImmutableList(dst)
 	}
 }
 
@@ -260,42 +272,42 @@ func (t immutableListValue) putAt(i, n int, val Value) List {
 		j = n
 	}
 	dst := make([]Value, j)
-	copy(dst, t)
+	copy(dst, t.list)
 	dst[i] = val
-	return immutableListValue(dst)
+	return newImmutableList(dst)
 }
 
 func (t immutableListValue) insertAt(i, n int, val Value) List {
 	if i == 0 {
 		dst := make([]Value, n+1)
-		copy(dst[1:], t)
+		copy(dst[1:], t.list)
 		dst[0] = val
-		return immutableListValue(dst)
+		return newImmutableList(dst)
 	} else if i+1 == n {
 		dst := make([]Value, n+1)
-		copy(dst, t[:i])
+		copy(dst, t.list[:i])
 		dst[n-1] = val
-		dst[n] = t[i]
-		return immutableListValue(dst)
+		dst[n] = t.list[i]
+		return newImmutableList(dst)
 	} else {
 		dst := make([]Value, n+1)
-		copy(dst, t[:i])
+		copy(dst, t.list[:i])
 		dst[i] = val
-		copy(dst[i+1:], t[i:])
-		return immutableListValue(dst)
+		copy(dst[i+1:], t.list[i:])
+		return newImmutableList(dst)
 	}
 }
 
 func (t immutableListValue) removeAt(i, n int) List {
 	if i == 0 {
-		return immutableListValue(t.copyOf(t[1:]))
+		return newImmutableList(t.copyOf(t.list[1:]))
 	} else if i+1 == n {
-		return immutableListValue(t.copyOf(t[:i]))
+		return newImmutableList(t.copyOf(t.list[:i]))
 	} else {
 		dst := make([]Value, n-1)
-		copy(dst, t[:i])
-		copy(dst[i:], t[i+1:])
-		return immutableListValue(dst)
+		copy(dst, t.list[:i])
+		copy(dst[i:], t.list[i+1:])
+		return newImmutableList(dst)
 	}
 }
 
@@ -320,7 +332,7 @@ func (t immutableListValue) InsertAll(i int, list []Value) List {
 	}
 
 	if i >= 0 {
-		n := len(t)
+		n := len(t.list)
 		if i < n {
 			return t.insertSliceAt(i, n, list)
 		} else {
@@ -336,12 +348,12 @@ func (t immutableListValue) DeleteAll(i int) List {
 
 func (t immutableListValue) appendSlice(n int, slice []Value) List {
 	if n == 0 {
-		return immutableListValue(t.copyOf(slice))
+		return newImmutableList(t.copyOf(slice))
 	} else {
 		dst := make([]Value, n+len(slice))
-		copy(dst, t)
+		copy(dst, t.list)
 		copy(dst[n:], slice)
-		return immutableListValue(dst)
+		return newImmutableList(dst)
 	}
 }
 
@@ -350,15 +362,15 @@ func (t immutableListValue) insertSliceAt(i, n int, slice []Value) List {
 		m := len(slice)
 		dst := make([]Value, m+n)
 		copy(dst, slice)
-		copy(dst[m:], t)
-		return immutableListValue(dst)
+		copy(dst[m:], t.list)
+		return newImmutableList(dst)
 	} else {
 		m := len(slice)
 		dst := make([]Value, n+m)
-		copy(dst, t[:i])
+		copy(dst, t.list[:i])
 		copy(dst[i:], slice)
-		copy(dst[i+m:], t[i:])
-		return immutableListValue(dst)
+		copy(dst[i+m:], t.list[i:])
+		return newImmutableList(dst)
 	}
 }
 
@@ -368,3 +380,7 @@ func (t immutableListValue) copyOf(src []Value) []Value {
 	copy(dst, src)
 	return dst
 }
+
+func (t immutableListValue) digest(hash crypto.Hash, canonical bool) ([]byte, []byte) {
+	return t.memo.load().digest(hash, canonical, t.immutableChildren, t.pack)
+}
@@ -7,6 +7,7 @@ package value
 
 import (
 	"bytes"
+	"crypto"
 	"reflect"
 	"sort"
 	"strings"
@@ -18,12 +19,19 @@ This is an immutable sorted Map implementation with deterministic serialization
 Serializes in MessagePack as Map with string index
 */
 
-type immutableMapValue []MapEntry
+type immutableMapValue struct {
+	list  []MapEntry
+	memo  *memoRef
+}
+
+func newImmutableMap(list []MapEntry) immutableMapValue {
+	return immutableMapValue{list: list, memo: newMemoRef()}
+}
 
 var immutableMapValueClass = reflect.TypeOf((*immutableMapValue)(nil)).Elem()
 
 func EmptyImmutableMap() Map {
-	return immutableMapValue([]MapEntry{})
+	return newImmutableMap([]MapEntry{})
 }
 
 func ImmutableMapOf(src map[string]Value) Map {
@@ -33,13 +41,13 @@ func ImmutableMapOf(src map[string]Value) Map {
 		entries[i] = ImmutableEntry(key, value)
 		i++
 	}
-	t := immutableMapValue(entries)
+	t := newImmutableMap(entries)
 	sort.Sort(t)
 	return t
 }
 
 func ImmutableMap(entries []MapEntry, sorted bool) Map {
-	t := immutableMapValue(entries)
+	t := newImmutableMap(entries)
 	if !sorted {
 		sort.Sort(t)
 	}
@@ -47,25 +55,25 @@ func ImmutableMap(entries []MapEntry, sorted bool) Map {
 }
 
 func ImmutableMapCopyOf(other Map) Map {
-	t := immutableMapValue(mapEntryCopyOf(other.Entries()))
+	t := newImmutableMap(mapEntryCopyOf(other.Entries()))
 	return t
 }
 
 func (t immutableMapValue) HashMap() map[string]Value {
 	cache := make(map[string]Value)
-	for _, entry := range t {
+	for _, entry := range t.list {
 		cache[entry.Key()] = entry.Value()
 	}
 	return cache
 }
 
 func (t immutableMapValue) Entries() []MapEntry {
-	return t
+	return t.list
 }
 
 func (t immutableMapValue) Keys() []string {
 	var keys []string
-	for _, entry := range t {
+	for _, entry := range t.list {
 		keys = append(keys, entry.Key())
 	}
 	return keys
@@ -73,22 +81,22 @@ func (t immutableMapValue) Keys() []string {
 
 func (t immutableMapValue) Values() []Value {
 	var values []Value
-	for _, entry := range t {
+	for _, entry := range t.list {
 		values = append(values, entry.Value())
 	}
 	return values
 }
 
 func (t immutableMapValue) Len() int {
-	return len(t)
+	return len(t.list)
 }
 
 func (t immutableMapValue) Swap(i, j int) {
-	t[i], t[j] = t[j], t[i]
+	t.list[i], t.list[j] = t.list[j], t.list[i]
 }
 
 func (t immutableMapValue) Less(i, j int) bool {
-	return t[i].Key() < t[j].Key()
+	return t.list[i].Key() < t.list[j].Key()
 }
 
 func (t immutableMapValue) Kind() Kind {
@@ -100,7 +108,7 @@ func (t immutableMapValue) Class() reflect.Type {
 }
 
 func (t immutableMapValue) Object() interface{} {
-	return []MapEntry(t)
+	return t.list
 }
 
 func (t immutableMapValue) String() string {
@@ -110,10 +118,14 @@ func (t immutableMapValue) String() string {
 }
 
 func (t immutableMapValue) Pack(p Packer) {
+	t.memo.pack(p, t.immutableChildren, t.pack)
+}
 
-	p.PackMap(len(t))
+func (t immutableMapValue) pack(p Packer) {
 
-	for _, entry := range t {
+	p.PackMap(len(t.list))
+
+	for _, entry := range t.list {
 		p.PackStr(entry.Key())
 		value := entry.Value()
 		if value != nil {
@@ -128,7 +140,7 @@ func (t immutableMapValue) Pack(p Packer) {
 func (t immutableMapValue) PrintJSON(out *strings.Builder) {
 
 	out.WriteRune('{')
-	for i, entry := range t {
+	for i, entry := range t.list {
 		if i != 0 {
 			out.WriteRune(',')
 		}
@@ -164,13 +176,16 @@ func (t immutableMapValue) Equal(val Value) bool {
 	if val == nil || val.Kind() != MAP {
 		return false
 	}
+	if vm, ok := val.(ValueMap); ok {
+		return vm.Equal(t)
+	}
 	o := val.(Map)
 	if t.Len() != o.Len() {
 		return false
 	}
 	// entries are sorted
 	other := o.Entries()
-	for i, entry := range t {
+	for i, entry := range t.list {
 		if !entry.Equal(other[i]) {
 			return false
 		}
@@ -179,14 +194,14 @@ func (t immutableMapValue) Equal(val Value) bool {
 }
 
 func (t immutableMapValue) Get(key string) Value {
-	n := len(t)
+	n := len(t.list)
 	i := sort.Search(n, func(i int) bool {
-		return t[i].Key() >= key
+		return t.list[i].Key() >= key
 	})
 	if i == n {
 		return Null
-	} else if t[i].Key() == key {
-		return t[i].Value()
+	} else if t.list[i].Key() == key {
+		return t.list[i].Value()
 	} else {
 		return Null
 	}
@@ -255,9 +270,9 @@ func (t immutableMapValue) Insert(key string, value Value) Map {
 	if value == nil {
 		value = Null
 	}
-	n := len(t)
+	n := len(t.list)
 	i := sort.Search(n, func(i int) bool {
-		return t[i].Key() >= key
+		return t.list[i].Key() >= key
 	})
 	if i == n {
 		return t.append(n, ImmutableEntry(key, value))
@@ -270,13 +285,13 @@ func (t immutableMapValue) Put(key string, value Value) Map {
 	if value == nil {
 		value = Null
 	}
-	n := len(t)
+	n := len(t.list)
 	i := sort.Search(n, func(i int) bool {
-		return t[i].Key() >= key
+		return t.list[i].Key() >= key
 	})
 	if i == n {
 		return t.append(n, ImmutableEntry(key, value))
-	} else if t[i].Key() == key {
+	} else if t.list[i].Key() == key {
 		return t.replaceAt(i, n, ImmutableEntry(key, value))
 	} else {
 		return t.insertAt(i, n, ImmutableEntry(key, value))
@@ -288,13 +303,13 @@ func (t immutableMapValue) Update(key string, updater Updater) bool {
 }
 
 func (t immutableMapValue) Remove(key string) Map {
-	n := len(t)
+	n := len(t.list)
 	i := sort.Search(n, func(i int) bool {
-		return t[i].Key() >= key
+		return t.list[i].Key() >= key
 	})
 	if i == n {
 		return t
-	} else if t[i].Key() == key {
+	} else if t.list[i].Key() == key {
 		return t.removeAt(i, n)
 	} else {
 		return t
@@ -303,64 +318,64 @@ func (t immutableMapValue) Remove(key string) Map {
 
 func (t immutableMapValue) append(n int, entry MapEntry) Map {
 	if n == 0 {
-		return immutableMapValue([]MapEntry{entry})
+		return newImmutableMap([]MapEntry{entry})
 	} else {
 		dst := make([]MapEntry, n+1)
-		copy(dst, t)
+		copy(dst, t.list)
 		dst[n] = entry
-		return immutableMapValue(dst)
+		return newImmutableMap(dst)
 	}
 }
 
 func (t immutableMapValue) replaceAt(i, n int, entry MapEntry) Map {
 	dst := make([]MapEntry, n)
-	copy(dst, t)
+	copy(dst, t.list)
 	dst[i] = entry
-	return immutableMapValue(dst)
+	return newImmutableMap(dst)
 }
 
 func (t immutableMapValue) insertAt(i, n int, entry MapEntry) Map {
 	if i == 0 {
 		dst := make([]MapEntry, n+1)
-		copy(dst[1:], t)
+		copy(dst[1:], t.list)
 		dst[0] = entry
-		return immutableMapValue(dst)
+		return newImmutableMap(dst)
 	} else if i+1 == n {
 		dst := make([]MapEntry, n+1)
-		copy(dst, t[:i])
+		copy(dst, t.list[:i])
 		dst[n-1] = entry
-		dst[n] = t[i]
-		return immutableMapValue(dst)
+		dst[n] = t.list[i]
+		return newImmutableMap(dst)
 	} else {
 		dst := make([]MapEntry, n+1)
-		copy(dst, t[:i])
+		copy(dst, t.list[:i])
 		dst[i] = entry
-		copy(dst[i+1:], t[i:])
-		return immutableMapValue(dst)
+		copy(dst[i+1:], t.list[i:])
+		return newImmutableMap(dst)
 	}
 }
 
 func (t immutableMapValue) removeAt(i, n int) Map {
 	if i == 0 {
-		return t[1:]
+		return newImmutableMap(t.list[1:])
 	} else if i+1 == n {
-		return t[:i]
+		return newImmutableMap(t.list[:i])
 	}  else {
 		dst := make([]MapEntry, n-1)
-		copy(dst, t[:i])
-		copy(dst[i:], t[i+1:])
-		return immutableMapValue(dst)
+		copy(dst, t.list[:i])
+		copy(dst[i:], t.list[i+1:])
+		return newImmutableMap(dst)
 	}
 }
 
 func (t immutableMapValue) Select(key string) []Value {
-	n := len(t)
+	n := len(t.list)
 	i := sort.Search(n, func(i int) bool {
-		return t[i].Key() >= key
+		return t.list[i].Key() >= key
 	})
 	var list []Value
-	for j := i; j < n && t[j].Key() == key; j++ {
-		list = append(list, t[j].Value())
+	for j := i; j < n && t.list[j].Key() == key; j++ {
+		list = append(list, t.list[j].Value())
 	}
 	return list
 }
@@ -382,9 +397,9 @@ func (t immutableMapValue) InsertAll(key string, list []Value) Map {
 		slice[i] = &immutableMapEntry{key, value}
 	}
 
-	n := len(t)
+	n := len(t.list)
 	i := sort.Search(n, func(i int) bool {
-		return t[i].Key() >= key
+		return t.list[i].Key() >= key
 	})
 	if i == n {
 		return t.appendSlice(n, slice)
@@ -394,15 +409,15 @@ func (t immutableMapValue) InsertAll(key string, list []Value) Map {
 }
 
 func (t immutableMapValue) DeleteAll(key string) Map {
-	n := len(t)
+	n := len(t.list)
 	i := sort.Search(n, func(i int) bool {
-		return t[i].Key() >= key
+		return t.list[i].Key() >= key
 	})
 	if i == n {
 		return t
 	}
 	cnt := 0
-	for j := i; j < n && t[j].Key() == key; j++ {
+	for j := i; j < n && t.list[j].Key() == key; j++ {
 		cnt++
 	}
 	return t.removeSliceAt(i, cnt, n)
@@ -410,12 +425,12 @@ func (t immutableMapValue) DeleteAll(key string) Map {
 
 func (t immutableMapValue) appendSlice(n int, slice []MapEntry) Map {
 	if n == 0 {
-		return immutableMapValue(mapEntryCopyOf(slice))
+		return newImmutableMap(mapEntryCopyOf(slice))
 	} else {
 		dst := make([]MapEntry, n+len(slice))
-		copy(dst, t)
+		copy(dst, t.list)
 		copy(dst[n:], slice)
-		return immutableMapValue(dst)
+		return newImmutableMap(dst)
 	}
 }
 
@@ -424,28 +439,32 @@ func (t immutableMapValue) insertSliceAt(i, n int, slice []MapEntry) Map {
 		m := len(slice)
 		dst := make([]MapEntry, m+n)
 		copy(dst, slice)
-		copy(dst[m:], t)
-		return immutableMapValue(dst)
+		copy(dst[m:], t.list)
+		return newImmutableMap(dst)
 	} else {
 		m := len(slice)
 		dst := make([]MapEntry, n+m)
-		copy(dst, t[:i])
+		copy(dst, t.list[:i])
 		copy(dst[i:], slice)
-		copy(dst[i+m:], t[i:])
-		return immutableMapValue(dst)
+		copy(dst[i+m:], t.list[i:])
+		return newImmutableMap(dst)
 	}
 }
 
 func (t immutableMapValue) removeSliceAt(i, cnt, n int) Map {
 	if i == 0 {
-		return immutableMapValue(mapEntryCopyOf(t[cnt:]))
+		return newImmutableMap(mapEntryCopyOf(t.list[cnt:]))
 	} else if i+cnt == n {
-		return immutableMapValue(mapEntryCopyOf(t[:i]))
+		return newImmutableMap(mapEntryCopyOf(t.list[:i]))
 	} else {
 		dst := make([]MapEntry, n-cnt)
-		copy(dst, t[:i])
-		copy(dst[i:], t[i+cnt:])
-		return immutableMapValue(dst)
+		copy(dst, t.list[:i])
+		copy(dst[i:], t.list[i+cnt:])
+		return newImmutableMap(dst)
 	}
 }
 
+
+func (t immutableMapValue) digest(hash crypto.Hash, canonical bool) ([]byte, []byte) {
+	return t.memo.load().digest(hash, canonical, t.immutableChildren, t.pack)
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"bytes"
+	"crypto"
+	"sync"
+	"sync/atomic"
+)
+
+/**
+	Enables cache of packed bytes and digests in immutable containers, so repeated Pack and Hash of the same container
+	and packing of parents with unchanged children do not encode the children again
+
+	Only containers without mutable containers or entries in the subtree are cached, the cache lives as long as
+	the container and holds the packed bytes of every nested level, so enable it when the same trees are hashed often
+*/
+
+var MemoizeContainers = false
+
+/**
+	Reference to the memo shared by all copies of the container, the memo is allocated on the first use,
+	so containers that are never hashed or packed with MemoizeContainers cost only the reference
+*/
+
+type memoRef struct {
+	v  atomic.Value   // *containerMemo
+}
+
+func newMemoRef() *memoRef {
+	return new(memoRef)
+}
+
+/**
+	Returns the memo of the container, allocates it on the first call, nil for containers without the reference
+*/
+
+func (r *memoRef) load() *containerMemo {
+	if r == nil {
+		return nil
+	}
+	if m, ok := r.v.Load().(*containerMemo); ok {
+		return m
+	}
+	r.v.CompareAndSwap(nil, new(containerMemo))
+	return r.v.Load().(*containerMemo)
+}
+
+/**
+	Lazily computed data of the immutable containers
+*/
+
+type containerMemo struct {
+	deepOnce  sync.Once
+	deep      bool        // no mutable containers or entries in the subtree
+
+	hashOnce  sync.Once
+	hash      uint64
+
+	mu        sync.Mutex
+	packedBy  [2][]byte   // by canonical numbers flag
+	digests   map[digestKey][]byte
+}
+
+/**
+	Checks once that the subtree of the container is immutable, only such containers could cache anything
+*/
+
+func (m *containerMemo) immutable(children func() bool) bool {
+	if m == nil {
+		return children()
+	}
+	m.deepOnce.Do(func() {
+		m.deep = children()
+	})
+	return m.deep
+}
+
+/**
+	Scalars and immutable containers with immutable subtrees never change
+*/
+
+func isImmutable(val Value) bool {
+	switch v := val.(type) {
+	case nil:
+		return true
+	case immutableListValue:
+		return v.memo.load().immutable(v.immutableChildren)
+	case immutableSetValue:
+		return v.memo.load().immutable(v.immutableChildren)
+	case immutableMapValue:
+		return v.memo.load().immutable(v.immutableChildren)
+	case immutableValueMap:
+		return v.memo.load().immutable(v.immutableChildren)
+	}
+	switch val.Kind() {
+	case LIST, MAP:
+		return false
+	default:
+		return true
+	}
+}
+
+type digestKey struct {
+	hash       crypto.Hash
+	canonical  bool
+}
+
+type digester interface {
+	digest(hash crypto.Hash, canonical bool) ([]byte, []byte)
+}
+
+func canonicalOption(options []PackOption) bool {
+	var p messagePacker
+	for _, opt := range options {
+		opt(&p)
+	}
+	return p.canonical
+}
+
+/**
+	Packs the container, the packer of this package gets the cached bytes of the immutable subtree,
+	other packers always get the structured calls, the memo is not touched while MemoizeContainers is off
+*/
+
+func (r *memoRef) pack(p Packer, children func() bool, pack func(Packer)) {
+	mp, ok := p.(*messagePacker)
+	if !ok || r == nil || !MemoizeContainers {
+		pack(p)
+		return
+	}
+	m := r.load()
+	if !m.immutable(children) {
+		pack(p)
+		return
+	}
+	mp.PackRaw(m.packedWith(mp.canonical, pack))
+}
+
+func packWith(canonical bool, pack func(Packer)) []byte {
+	var buf bytes.Buffer
+	mp := MessagePacker(&buf)
+	mp.canonical = canonical
+	pack(mp)
+	return buf.Bytes()
+}
+
+/**
+	Returns cached packed bytes of the container, the result must not be modified
+*/
+
+func (m *containerMemo) packedWith(canonical bool, pack func(Packer)) []byte {
+	i := boolToLong(canonical)
+
+	m.mu.Lock()
+	b := m.packedBy[i]
+	m.mu.Unlock()
+	if b != nil {
+		return b
+	}
+
+	b = packWith(canonical, pack)
+
+	m.mu.Lock()
+	if m.packedBy[i] == nil {
+		m.packedBy[i] = b
+	} else {
+		b = m.packedBy[i]
+	}
+	m.mu.Unlock()
+	return b
+}
+
+/**
+	Returns packed bytes and digest of the container, cached only for the immutable subtree, the results must not be modified
+*/
+
+func (m *containerMemo) digest(hash crypto.Hash, canonical bool, children func() bool, pack func(Packer)) ([]byte, []byte) {
+
+	if m == nil || !m.immutable(children) {
+		data := packWith(canonical, pack)
+		h := hash.New()
+		h.Write(data)
+		return data, h.Sum(nil)
+	}
+
+	data := m.packedWith(canonical, pack)
+	key := digestKey{hash, canonical}
+
+	m.mu.Lock()
+	sum, ok := m.digests[key]
+	m.mu.Unlock()
+	if ok {
+		return data, sum
+	}
+
+	h := hash.New()
+	h.Write(data)
+	sum = h.Sum(nil)
+
+	m.mu.Lock()
+	if m.digests == nil {
+		m.digests = make(map[digestKey][]byte)
+	}
+	m.digests[key] = sum
+	m.mu.Unlock()
+	return data, sum
+}
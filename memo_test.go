@@ -0,0 +1,204 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/stretchr/testify/require"
+)
+
+type countingValue struct {
+	val.Value
+	packs *int
+}
+
+func (v countingValue) Pack(p val.Packer) {
+	*v.packs++
+	v.Value.Pack(p)
+}
+
+type rawCountingPacker struct {
+	val.Packer
+	raws int
+}
+
+func (p *rawCountingPacker) PackRaw(b []byte) {
+	p.raws++
+	p.Packer.PackRaw(b)
+}
+
+func memoizeContainers(t *testing.T) {
+	val.MemoizeContainers = true
+	t.Cleanup(func() { val.MemoizeContainers = false })
+}
+
+func TestMemoizedDigest(t *testing.T) {
+
+	memoizeContainers(t)
+
+	packs := 0
+	child := val.ImmutableList([]val.Value{ countingValue{val.Utf8("large subtree"), &packs}, val.Long(1) })
+
+	data, digest, err := val.Hash(child, crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, 1, packs)
+
+	data2, digest2, err := val.Hash(child, crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, 1, packs)
+	require.Equal(t, data, data2)
+	require.Equal(t, digest, digest2)
+
+	// returned slices are copies
+	data2[0] = 0
+	digest2[0]++
+	data3, digest3, err := val.Hash(child, crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, data, data3)
+	require.Equal(t, digest, digest3)
+
+	// parents reuse packed bytes of unchanged children
+	parent := val.EmptyImmutableMap().Put("child", child).Put("version", val.Long(1))
+	_, _, err = val.Hash(parent, crypto.SHA256)
+	require.NoError(t, err)
+	next := parent.Put("version", val.Long(2))
+	_, _, err = val.Hash(next, crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, 1, packs)
+
+	// cached bytes are the same as packed without memo
+	val.MemoizeContainers = false
+
+	plainData, plainDigest, err := val.Hash(child, crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, 2, packs)
+	require.Equal(t, data, plainData)
+	require.Equal(t, digest, plainDigest)
+
+}
+
+func TestMemoizeDisabledByDefault(t *testing.T) {
+
+	require.False(t, val.MemoizeContainers)
+
+	packs := 0
+	list := val.ImmutableList([]val.Value{ countingValue{val.Utf8("a"), &packs} })
+	_, _, err := val.Hash(list, crypto.SHA256)
+	require.NoError(t, err)
+	_, _, err = val.Hash(list, crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, 2, packs)
+
+}
+
+func TestMemoizedMutableChild(t *testing.T) {
+
+	memoizeContainers(t)
+
+	inner := val.EmptyMap(false).Put("a", val.Long(1))
+	outer := val.ImmutableList([]val.Value{ inner })
+	require.Equal(t, "9181a16101", val.Hex(outer))
+	_, digest, err := val.Hash(outer, crypto.SHA256)
+	require.NoError(t, err)
+
+	require.True(t, inner.Update("a", setValue{val.Long(2)}))
+
+	require.Equal(t, "9181a16102", val.Hex(outer))
+	require.Equal(t, `[{"a": 2}]`, val.Jsonify(outer))
+	_, digest2, err := val.Hash(outer, crypto.SHA256)
+	require.NoError(t, err)
+	require.NotEqual(t, digest, digest2)
+
+	// immutable siblings are still cached
+	packs := 0
+	shared := val.ImmutableList([]val.Value{ countingValue{val.Utf8("x"), &packs} })
+	mixed := val.ImmutableList([]val.Value{ shared, inner })
+	val.Hex(mixed)
+	val.Hex(mixed)
+	require.Equal(t, 1, packs)
+
+}
+
+func TestMemoizedCustomPacker(t *testing.T) {
+
+	memoizeContainers(t)
+
+	list := val.ImmutableList([]val.Value{ val.Utf8("a"), val.Tuple(val.Long(1)) })
+	// fills the cache
+	expected := val.Hex(list)
+
+	// other packers get the structured calls
+	var buf bytes.Buffer
+	p := &rawCountingPacker{Packer: val.MessagePacker(&buf)}
+	list.Pack(p)
+	require.NoError(t, p.Error())
+	require.Equal(t, 0, p.raws)
+	require.Equal(t, expected, hex.EncodeToString(buf.Bytes()))
+
+}
+
+func TestMemoizedCanonical(t *testing.T) {
+
+	memoizeContainers(t)
+
+	list := val.ImmutableList([]val.Value{ val.Double(1) })
+
+	plain, err := val.Pack(list)
+	require.NoError(t, err)
+	canonical, err := val.Pack(list, val.WithCanonicalNumbers())
+	require.NoError(t, err)
+	require.Equal(t, "91cb3ff0000000000000", val.Hex(list))
+	require.Equal(t, []byte{ 0x91, 0x01 }, canonical)
+	require.NotEqual(t, plain, canonical)
+
+	_, d1, err := val.Hash(list, crypto.SHA256)
+	require.NoError(t, err)
+	_, d2, err := val.Hash(list, crypto.SHA256, val.WithCanonicalNumbers())
+	require.NoError(t, err)
+	_, d3, err := val.Hash(val.ImmutableList([]val.Value{ val.Long(1) }), crypto.SHA256)
+	require.NoError(t, err)
+	require.NotEqual(t, d1, d2)
+	require.Equal(t, d2, d3)
+
+}
+
+func TestMemoizedConcurrentFirstUse(t *testing.T) {
+
+	memoizeContainers(t)
+
+	list := val.ImmutableList([]val.Value{ val.Utf8("shared"), val.Long(1) })
+	copies := []val.Value{ list, list, list, list }
+
+	// copies taken before the first use share the memo allocated by any of them
+	var wg sync.WaitGroup
+	codes := make([]uint64, len(copies))
+	digests := make([][]byte, len(copies))
+	for i, c := range copies {
+		wg.Add(1)
+		go func(i int, c val.Value) {
+			defer wg.Done()
+			codes[i] = val.HashCode(c)
+			_, digests[i], _ = val.Hash(c, crypto.SHA256)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for i := range copies {
+		require.Equal(t, codes[0], codes[i])
+		require.Equal(t, digests[0], digests[i])
+	}
+
+	_, digest, err := val.Hash(list, crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, digests[0], digest)
+
+}
@@ -0,0 +1,307 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/binary"
+	"github.com/pkg/errors"
+)
+
+/**
+	Merkle hashing of values
+
+	scalar     H(0x00 | packed with canonical numbers)
+	entry      H(0x01 | uvarint key length | key | node digest of the value)
+	tree       H(0x02 | left | right), odd node is promoted to the next level, H(0x02) for no entries
+	container  H(0x03 | kind | uvarint count | tree of entries)
+
+	Entries are taken from Entries() for lists and maps, so list keys are indexes. Domain separation bytes
+	make leaves, inner nodes and containers different even if their inputs are equal.
+*/
+
+const (
+	merkleScalar    byte = 0
+	merkleEntry     byte = 1
+	merkleTree      byte = 2
+	merkleContainer byte = 3
+)
+
+var ErrProof = errors.New("invalid merkle proof")
+
+/**
+	Step of the proof for one container on the path, from the root to the leaf
+*/
+
+type MerkleStep struct {
+	Kind      Kind
+	Count     int
+	Index     int
+	Siblings  [][]byte   // bottom-up digests of the sibling nodes in the tree of entries
+}
+
+type MerkleProof struct {
+	Hash   crypto.Hash
+	Steps  []MerkleStep
+}
+
+/**
+	Returns merkle root digest of the value
+*/
+
+func MerkleRoot(val Value, hash crypto.Hash) ([]byte, error) {
+	if !hash.Available() {
+		return nil, errors.Errorf("hash function %v is not linked", hash)
+	}
+	return merkleNode(val, hash), nil
+}
+
+func merkleNode(val Value, hash crypto.Hash) []byte {
+	switch kindOf(val) {
+	case LIST, MAP:
+		entries := val.(Collection).Entries()
+		leaves := make([][]byte, len(entries))
+		for i, e := range entries {
+			leaves[i] = merkleEntryDigest(hash, e.Key(), merkleNode(e.Value(), hash))
+		}
+		return merkleContainerDigest(hash, kindOf(val), len(entries), merkleTreeRoot(hash, leaves))
+	default:
+		h := hash.New()
+		h.Write([]byte{ merkleScalar })
+		if val == nil {
+			val = Null
+		}
+		b, _ := Pack(val, WithCanonicalNumbers())
+		h.Write(b)
+		return h.Sum(nil)
+	}
+}
+
+func merkleEntryDigest(hash crypto.Hash, key string, digest []byte) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	h := hash.New()
+	h.Write([]byte{ merkleEntry })
+	h.Write(buf[:binary.PutUvarint(buf[:], uint64(len(key)))])
+	h.Write([]byte(key))
+	h.Write(digest)
+	return h.Sum(nil)
+}
+
+func merkleContainerDigest(hash crypto.Hash, kind Kind, count int, tree []byte) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	h := hash.New()
+	h.Write([]byte{ merkleContainer, byte(kind) })
+	h.Write(buf[:binary.PutUvarint(buf[:], uint64(count))])
+	h.Write(tree)
+	return h.Sum(nil)
+}
+
+func merkleInner(hash crypto.Hash, left, right []byte) []byte {
+	h := hash.New()
+	h.Write([]byte{ merkleTree })
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+func merkleTreeRoot(hash crypto.Hash, level [][]byte) []byte {
+	if len(level) == 0 {
+		h := hash.New()
+		h.Write([]byte{ merkleTree })
+		return h.Sum(nil)
+	}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level) + 1) / 2)
+		for i := 0; i < len(level); i += 2 {
+			if i + 1 < len(level) {
+				next = append(next, merkleInner(hash, level[i], level[i+1]))
+			} else {
+				next = append(next, level[i])
+			}
+		}
+		level = next
+	}
+	return level[0]
+}
+
+/**
+	Returns bottom-up siblings of the leaf at the index
+*/
+
+func merkleSiblings(hash crypto.Hash, level [][]byte, index int) [][]byte {
+	var siblings [][]byte
+	for len(level) > 1 {
+		if sib := index ^ 1; sib < len(level) {
+			siblings = append(siblings, level[sib])
+		}
+		next := make([][]byte, 0, (len(level) + 1) / 2)
+		for i := 0; i < len(level); i += 2 {
+			if i + 1 < len(level) {
+				next = append(next, merkleInner(hash, level[i], level[i+1]))
+			} else {
+				next = append(next, level[i])
+			}
+		}
+		level = next
+		index /= 2
+	}
+	return siblings
+}
+
+/**
+	Builds inclusion proof of the value at the path, path segments are map keys and list indexes
+*/
+
+func Prove(val Value, path []string, hash crypto.Hash) (*MerkleProof, error) {
+
+	if !hash.Available() {
+		return nil, errors.Errorf("hash function %v is not linked", hash)
+	}
+
+	proof := &MerkleProof{Hash: hash}
+
+	for depth, seg := range path {
+
+		kind := kindOf(val)
+		if kind != LIST && kind != MAP {
+			return nil, errors.Errorf("value at path %v is %v, not a container", path[:depth], kind)
+		}
+
+		entries := val.(Collection).Entries()
+		index := -1
+		leaves := make([][]byte, len(entries))
+		for i, e := range entries {
+			if index == -1 && e.Key() == seg {
+				index = i
+			}
+			leaves[i] = merkleEntryDigest(hash, e.Key(), merkleNode(e.Value(), hash))
+		}
+		if index == -1 {
+			return nil, errors.Errorf("key '%s' not found at path %v", seg, path[:depth])
+		}
+
+		proof.Steps = append(proof.Steps, MerkleStep{
+			Kind: kind,
+			Count: len(entries),
+			Index: index,
+			Siblings: merkleSiblings(hash, leaves, index),
+		})
+
+		val = entries[index].Value()
+	}
+
+	return proof, nil
+}
+
+/**
+	Checks that the leaf is at the path of the value with the root digest, returns ErrProof if not
+
+	Hash function is chosen by the verifier, proof made by the other hash function is rejected
+*/
+
+func VerifyProof(root []byte, path []string, leaf Value, proof *MerkleProof, hash crypto.Hash) error {
+
+	if !hash.Available() {
+		return errors.Errorf("hash function %v is not linked", hash)
+	}
+	if proof == nil || len(proof.Steps) != len(path) {
+		return errors.Wrap(ErrProof, "proof does not match the path")
+	}
+	if proof.Hash != hash {
+		return errors.Wrapf(ErrProof, "proof hash function %v, expected %v", proof.Hash, hash)
+	}
+
+	digest := merkleNode(leaf, hash)
+
+	for i := len(path) - 1; i >= 0; i-- {
+		step := proof.Steps[i]
+		if step.Index < 0 || step.Index >= step.Count {
+			return errors.Wrapf(ErrProof, "index %d out of %d", step.Index, step.Count)
+		}
+
+		// the key is bound by the entry digest, position of the entry is not the key in sparse lists
+		node := merkleEntryDigest(hash, path[i], digest)
+		index, width, siblings := step.Index, step.Count, step.Siblings
+		for width > 1 {
+			if sib := index ^ 1; sib < width {
+				if len(siblings) == 0 {
+					return errors.Wrap(ErrProof, "not enough siblings")
+				}
+				if index & 1 == 0 {
+					node = merkleInner(hash, node, siblings[0])
+				} else {
+					node = merkleInner(hash, siblings[0], node)
+				}
+				siblings = siblings[1:]
+			}
+			index /= 2
+			width = (width + 1) / 2
+		}
+		if len(siblings) != 0 {
+			return errors.Wrap(ErrProof, "too many siblings")
+		}
+
+		digest = merkleContainerDigest(hash, step.Kind, step.Count, node)
+	}
+
+	if !bytes.Equal(digest, root) {
+		return ErrProof
+	}
+	return nil
+}
+
+/**
+	Converts proof to the value for transport, as [hash, [kind, count, index, [siblings]]...]
+*/
+
+func (p *MerkleProof) Value() Value {
+	steps := make([]Value, len(p.Steps))
+	for i, s := range p.Steps {
+		siblings := make([]Value, len(s.Siblings))
+		for j, sib := range s.Siblings {
+			siblings[j] = Raw(sib, false)
+		}
+		steps[i] = Tuple(Long(int64(s.Kind)), Long(int64(s.Count)), Long(int64(s.Index)), ImmutableList(siblings))
+	}
+	return Tuple(Long(int64(p.Hash)), ImmutableList(steps))
+}
+
+func ParseMerkleProof(val Value) (*MerkleProof, error) {
+	invalid := errors.Wrap(ErrProof, "malformed proof value")
+	list, ok := val.(List)
+	if !ok || list.Len() != 2 {
+		return nil, invalid
+	}
+	steps, ok := list.GetAt(1).(List)
+	if !ok || list.GetAt(0).Kind() != NUMBER {
+		return nil, invalid
+	}
+	p := &MerkleProof{Hash: crypto.Hash(list.GetNumberAt(0).Long())}
+	for _, el := range steps.Values() {
+		s, ok := el.(List)
+		if !ok || s.Len() != 4 {
+			return nil, invalid
+		}
+		siblings, ok := s.GetAt(3).(List)
+		if !ok {
+			return nil, invalid
+		}
+		step := MerkleStep{
+			Kind: Kind(s.GetNumberAt(0).Long()),
+			Count: int(s.GetNumberAt(1).Long()),
+			Index: int(s.GetNumberAt(2).Long()),
+		}
+		for _, sib := range siblings.Values() {
+			if sib.Kind() != STRING {
+				return nil, invalid
+			}
+			step.Siblings = append(step.Siblings, sib.(String).Raw())
+		}
+		p.Steps = append(p.Steps, step)
+	}
+	return p, nil
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func merkleDoc() val.Map {
+	return val.EmptyImmutableMap().
+		Put("name", val.Utf8("Alice")).
+		Put("age", val.Long(30)).
+		Put("email", val.Utf8("alice@example.com")).
+		Put("roles", val.Tuple(val.Utf8("admin"), val.Utf8("dev"), val.Utf8("ops"))).
+		Put("address", val.EmptyImmutableMap().Put("city", val.Utf8("Paris")).Put("zip", val.Utf8("75001")))
+}
+
+func TestMerkleRoot(t *testing.T) {
+
+	doc := merkleDoc()
+
+	root, err := val.MerkleRoot(doc, crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, 32, len(root))
+
+	// numbers in canonical form
+	same, err := val.MerkleRoot(doc.Put("age", val.Double(30)), crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, root, same)
+
+	other, err := val.MerkleRoot(doc.Put("age", val.Long(31)), crypto.SHA256)
+	require.NoError(t, err)
+	require.NotEqual(t, root, other)
+
+	// list and map with the same entries differ
+	l, _ := val.MerkleRoot(val.Tuple(val.Long(1)), crypto.SHA256)
+	m, _ := val.MerkleRoot(val.EmptyImmutableMap().Put("0", val.Long(1)), crypto.SHA256)
+	require.NotEqual(t, l, m)
+
+	_, err = val.MerkleRoot(doc, crypto.Hash(0))
+	require.Error(t, err)
+
+}
+
+func TestMerkleProof(t *testing.T) {
+
+	doc := merkleDoc()
+	root, err := val.MerkleRoot(doc, crypto.SHA256)
+	require.NoError(t, err)
+
+	for _, path := range [][]string {
+		{ "name" },
+		{ "email" },
+		{ "roles", "0" },
+		{ "roles", "2" },
+		{ "address", "zip" },
+		{ "address" },
+		{},
+	} {
+		proof, err := val.Prove(doc, path, crypto.SHA256)
+		require.NoError(t, err, "%v", path)
+
+		leaf := val.Value(doc)
+		for _, seg := range path {
+			leaf = leaf.(val.Collection).Entries()[indexOf(leaf, seg)].Value()
+		}
+		require.NoError(t, val.VerifyProof(root, path, leaf, proof, crypto.SHA256), "%v", path)
+
+		// proof survives transport
+		parsed, err := val.ParseMerkleProof(proof.Value())
+		require.NoError(t, err)
+		require.NoError(t, val.VerifyProof(root, path, leaf, parsed, crypto.SHA256))
+	}
+
+	proof, err := val.Prove(doc, []string{ "email" }, crypto.SHA256)
+	require.NoError(t, err)
+
+	err = val.VerifyProof(root, []string{ "email" }, val.Utf8("mallory@example.com"), proof, crypto.SHA256)
+	require.True(t, errors.Is(err, val.ErrProof))
+
+	err = val.VerifyProof(root, []string{ "name" }, val.Utf8("alice@example.com"), proof, crypto.SHA256)
+	require.True(t, errors.Is(err, val.ErrProof))
+
+	proof, err = val.Prove(doc, []string{ "roles", "1" }, crypto.SHA256)
+	require.NoError(t, err)
+	err = val.VerifyProof(root, []string{ "roles", "0" }, val.Utf8("dev"), proof, crypto.SHA256)
+	require.True(t, errors.Is(err, val.ErrProof))
+
+	// hash function is not taken from the proof
+	err = val.VerifyProof(root, []string{ "roles", "1" }, val.Utf8("dev"), proof, crypto.SHA512)
+	require.True(t, errors.Is(err, val.ErrProof))
+
+	forged, err := val.ParseMerkleProof(proof.Value())
+	require.NoError(t, err)
+	forged.Hash = crypto.SHA512
+	err = val.VerifyProof(root, []string{ "roles", "1" }, val.Utf8("dev"), forged, crypto.SHA256)
+	require.True(t, errors.Is(err, val.ErrProof))
+
+	err = val.VerifyProof(root, []string{ "roles", "1" }, val.Utf8("dev"), proof, crypto.Hash(0))
+	require.Error(t, err)
+	require.False(t, errors.Is(err, val.ErrProof))
+
+	// sparse list keys are not the positions of the entries
+	sparse := val.EmptyImmutableMap().Put("s", val.SparseList([]val.ListItem {
+		val.ImmutableItem(5, val.Utf8("a")),
+		val.ImmutableItem(10, val.Utf8("b")),
+	}, true))
+	sparseRoot, err := val.MerkleRoot(sparse, crypto.SHA256)
+	require.NoError(t, err)
+
+	proof, err = val.Prove(sparse, []string{ "s", "10" }, crypto.SHA256)
+	require.NoError(t, err)
+	require.NoError(t, val.VerifyProof(sparseRoot, []string{ "s", "10" }, val.Utf8("b"), proof, crypto.SHA256))
+
+	err = val.VerifyProof(sparseRoot, []string{ "s", "1" }, val.Utf8("b"), proof, crypto.SHA256)
+	require.True(t, errors.Is(err, val.ErrProof))
+
+	err = val.VerifyProof(sparseRoot, []string{ "s", "5" }, val.Utf8("b"), proof, crypto.SHA256)
+	require.True(t, errors.Is(err, val.ErrProof))
+
+	_, err = val.Prove(doc, []string{ "missing" }, crypto.SHA256)
+	require.Error(t, err)
+	_, err = val.Prove(doc, []string{ "name", "first" }, crypto.SHA256)
+	require.Error(t, err)
+
+}
+
+func indexOf(v val.Value, key string) int {
+	for i, e := range v.(val.Collection).Entries() {
+		if e.Key() == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -7,6 +7,8 @@
 package value
 
 import (
+	"bufio"
+	"bytes"
 	"io"
 	"math"
 	"encoding/binary"
@@ -79,6 +81,8 @@ const (
 	defWriteBufSize 	= 16
 	defReadBufSize 		= 24
 
+	mpMaxHeaderSize 	= 18  // mpFixExt16 with tag and data
+
 	mpCodeMin 			= mpNil
 	mpCodeMax 			= mpMap32
 )
@@ -182,40 +186,65 @@ var (
 )
 
 type messagePacker struct {
-	m   messageWriter
-	w   io.Writer
-	err error
+	m         messageWriter
+	w         io.Writer
+	err       error
+	canonical bool
+}
+
+/**
+	Options of the message packer
+*/
+
+type PackOption func(*messagePacker)
+
+/**
+	Packs numbers in the canonical form, so equal numbers of different types have the same encoding
+*/
+
+func WithCanonicalNumbers() PackOption {
+	return func(p *messagePacker) {
+		p.canonical = true
+	}
+}
+
+func MessagePacker(w io.Writer, options ...PackOption) *messagePacker {
+	p := &messagePacker{w: w}
+	for _, opt := range options {
+		opt(p)
+	}
+	return p
 }
 
-func MessagePacker(w io.Writer) *messagePacker {
-	return &messagePacker{w: w}
+func (p *messagePacker) canonicalNumbers() bool {
+	return p.canonical
 }
 
-func (p messagePacker) PackNil()  {
+func (p *messagePacker) PackNil()  {
 	if p.err == nil {
 		_, p.err = p.w.Write(p.m.WriteNil())
 	}
 }
 
-func (p messagePacker) PackBool(val bool) {
+func (p *messagePacker) PackBool(val bool) {
 	if p.err == nil {
 		_, p.err = p.w.Write(p.m.WriteBool(val))
 	}
 }
 
-func (p messagePacker) PackLong(val int64) {
+func (p *messagePacker) PackLong(val int64) {
 	if p.err == nil {
 		_, p.err = p.w.Write(p.m.WriteLong(val))
 	}
 }
 
-func (p messagePacker) PackDouble(val float64) {
+func (p *messagePacker) PackDouble(val float64) {
 	if p.err == nil {
 		_, p.err = p.w.Write(p.m.WriteDouble(val))
 	}
 }
 
-func (p messagePacker) PackStr(str string) {
+func (p *messagePacker) PackStr(str string) {
 	b := []byte(str)
 	if p.err == nil {
 		_, p.err = p.w.Write(p.m.WriteStrHeader(len(b)))
@@ -225,7 +254,7 @@ func (p messagePacker) PackStr(str string) {
 	}
 }
 
-func (p messagePacker) PackBin(b []byte) {
+func (p *messagePacker) PackBin(b []byte) {
 	if p.err == nil {
 		_, p.err = p.w.Write(p.m.WriteBinHeader(len(b)))
 	}
@@ -243,7 +272,7 @@ func (p *messagePacker) PackExt(xtag Ext, data []byte) {
 	}
 }
 
-func (p messagePacker) PackList(size int) {
+func (p *messagePacker) PackList(size int) {
 	if size < 0 {
 		size = 0
 	}
@@ -252,7 +281,7 @@ func (p messagePacker) PackList(size int) {
 	}
 }
 
-func (p messagePacker) PackMap(size int) {
+func (p *messagePacker) PackMap(size int) {
 	if size < 0 {
 		size = 0
 	}
@@ -261,7 +290,7 @@ func (p messagePacker) PackMap(size int) {
 	}
 }
 
-func (p messagePacker) PackRaw(b []byte) {
+func (p *messagePacker) PackRaw(b []byte) {
 	if p.err == nil {
 		_, p.err = p.w.Write(b)
 	}
@@ -434,7 +463,7 @@ func (p messageWriter) WriteArrayHeader(len int) []byte {
 		binary.BigEndian.PutUint16(p.buf[1:3], uint16(len))
 		return p.buf[:3]
 	default:
-		p.buf[0] = mpArray16
+		p.buf[0] = mpArray32
 		binary.BigEndian.PutUint32(p.buf[1:5], uint32(len))
 		return p.buf[:5]
 	}
@@ -671,7 +700,7 @@ func (p *messageBufUnpacker) Next() (Format, []byte) {
 func (p *messageBufUnpacker) Read(n int) ([]byte, error) {
 
 	if p.remaining() < n {
-		return nil, io.ErrUnexpectedEOF
+		return nil, errors.Wrapf(io.ErrUnexpectedEOF, "read %d bytes at offset %d", n, p.off)
 	}
 
 	b := p.buf[p.off:]
@@ -686,51 +715,202 @@ func (p *messageBufUnpacker) Read(n int) ([]byte, error) {
 	}
 }
 
+func (p messageBufUnpacker) Offset() int64 {
+	return int64(p.off)
+}
+
+func (p messageBufUnpacker) Error() error {
+	return nil
+}
+
+/**
+	Payloads bigger than this value are read incrementally, so corrupted length headers
+	from untrusted streams can not force huge allocations before the data actually arrives
+*/
+
+var maxReadPrealloc = 64 * 1024
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
 type messageIOUnpacker struct {
 	buf 	[defReadBufSize]byte
-	r       io.Reader
-	br      io.ByteReader  // can be null
+	r       byteReader
+	off     int64
+	err     error
 }
 
+/**
+	Creates unpacker on top of any reader
+
+	Readers that do not implement io.ByteReader are wrapped in bufio.Reader,
+	therefore the unpacker could consume more bytes from the source than the value takes
+*/
+
 func MessageReader(r io.Reader) *messageIOUnpacker {
-	return &messageIOUnpacker{r: r, br: r.(io.ByteReader)}
+	br, ok := r.(byteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &messageIOUnpacker{r: br}
 }
 
 func (p *messageIOUnpacker) Next() (Format, []byte) {
 
-	if p.br != nil {
-		code, err := p.br.ReadByte()
-		if err != nil {
-			return EOF, nil
+	if p.err != nil {
+		return EOF, nil
+	}
+
+	code, err := p.r.ReadByte()
+	if err != nil {
+		if err != io.EOF {
+			p.err = errors.Wrapf(err, "read code at offset %d", p.off)
 		}
-		p.buf[0] = code
-	}  else {
-		n, _ := p.r.Read(p.buf[:1])
-		if n == 0 {
-			return EOF, nil
+		return EOF, nil
+	}
+
+	format, len := nextFormat(code)
+	p.buf[0] = code
+
+	m, err := io.ReadFull(p.r, p.buf[1:1+len])
+	if err != nil {
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			p.err = errors.Wrapf(err, "read header at offset %d", p.off)
 		}
+		p.off += int64(1 + m)
+		return UnexpectedEOF, nil
+	}
+
+	p.off += int64(1 + len)
+	return format, p.buf[0:1+len]
+}
+
+func (p *messageIOUnpacker) Read(n int) ([]byte, error) {
+
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	var b []byte
+	var m int
+	var err error
+
+	if n <= maxReadPrealloc {
+		b = make([]byte, n)
+		m, err = io.ReadFull(p.r, b)
+	} else {
+		var buf bytes.Buffer
+		var c int64
+		c, err = io.CopyN(&buf, p.r, int64(n))
+		b, m = buf.Bytes(), int(c)
+	}
+
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		p.err = errors.Wrapf(err, "read %d bytes at offset %d", n, p.off + int64(m))
+		p.off += int64(m)
+		return nil, p.err
+	}
+
+	p.off += int64(n)
+	return b, nil
+}
+
+func (p messageIOUnpacker) Offset() int64 {
+	return p.off
+}
+
+func (p messageIOUnpacker) Error() error {
+	return p.err
+}
+
+type messageReaderAtUnpacker struct {
+	buf 	[defReadBufSize]byte
+	r       io.ReaderAt
+	off     int64
+	err     error
+}
+
+/**
+	Creates unpacker that reads values by absolute offsets without buffering or moving the source position
+*/
+
+func MessageReaderAt(r io.ReaderAt, off int64) *messageReaderAtUnpacker {
+	return &messageReaderAtUnpacker{r: r, off: off}
+}
+
+func (p *messageReaderAtUnpacker) Next() (Format, []byte) {
+
+	if p.err != nil {
+		return EOF, nil
+	}
+
+	// single call reads the code and the longest possible header
+	m, err := p.r.ReadAt(p.buf[:mpMaxHeaderSize], p.off)
+	if m == 0 {
+		if err != nil && err != io.EOF {
+			p.err = errors.Wrapf(err, "read code at offset %d", p.off)
+		}
+		return EOF, nil
 	}
 
 	format, len := nextFormat(p.buf[0])
 	n := 1 + len
-
-	m, _ := p.r.Read(p.buf[1:n])
-	if m != len {
+	if m < n {
+		if err != nil && err != io.EOF {
+			p.err = errors.Wrapf(err, "read header at offset %d", p.off)
+		}
+		p.off += int64(m)
 		return UnexpectedEOF, nil
 	}
 
+	p.off += int64(n)
 	return format, p.buf[0:n]
 }
 
-func (p *messageIOUnpacker) Read(n int) (b []byte, err error) {
-	b = make([]byte, n)
-	m, err := p.r.Read(b)
-	if m != n {
-		if err == nil {
+func (p *messageReaderAtUnpacker) Read(n int) ([]byte, error) {
+
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	var b []byte
+	var m int
+	var err error
+
+	if n <= maxReadPrealloc {
+		b = make([]byte, n)
+		m, err = p.r.ReadAt(b, p.off)
+	} else {
+		var buf bytes.Buffer
+		var c int64
+		c, err = io.CopyN(&buf, io.NewSectionReader(p.r, p.off, int64(n)), int64(n))
+		b, m = buf.Bytes(), int(c)
+	}
+
+	if m < n {
+		if err == nil || err == io.EOF {
 			err = io.ErrUnexpectedEOF
 		}
+		p.err = errors.Wrapf(err, "read %d bytes at offset %d", n, p.off + int64(m))
+		p.off += int64(m)
+		return nil, p.err
 	}
-	return b, err
+
+	p.off += int64(n)
+	return b, nil
+}
+
+func (p messageReaderAtUnpacker) Offset() int64 {
+	return p.off
+}
+
+func (p messageReaderAtUnpacker) Error() error {
+	return p.err
 }
 
 func nextFormat(code byte) (Format, int) {
@@ -9,10 +9,12 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/hex"
+	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"math"
 	"math/big"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -20,6 +22,10 @@ import (
 var DecimalExpDelim = byte('x')
 var DecimalExpDelimStr = "x"
 
+/**
+	Tolerance of TolerantEquality
+*/
+
 var PrecisionLevel = 0.00001
 
 type longNumber int64
@@ -103,6 +109,95 @@ func ParseNumber(str string) Number {
 
 }
 
+var decimalLiteral = regexp.MustCompile(`^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
+
+/**
+	Parses number without loss of precision
+
+	Integers become Long or BigInt if they do not fit int64, literals with fraction or exponent become Decimal.
+	Supports prefixes 0x (with the exponent in the format of Decimal.String), 0b and 0o, and NaN, Inf, +Inf, -Inf.
+*/
+
+func ParseNumberExact(str string) (Number, error) {
+
+	switch strings.ToLower(str) {
+	case "":
+		return nil, errors.New("empty number")
+	case "nan":
+		return Nan, nil
+	case "inf", "+inf":
+		return Double(math.Inf(1)), nil
+	case "-inf":
+		return Double(math.Inf(-1)), nil
+	}
+
+	s := str
+	neg := false
+	if s[0] == '-' || s[0] == '+' {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	if len(s) > 2 && s[0] == '0' {
+		base := 0
+		switch s[1] {
+		case 'x', 'X':
+			// sign is allowed only in the exponent
+			mantissa, exp, hasExp := s[2:], "", false
+			if i := strings.IndexByte(mantissa, DecimalExpDelim); i != -1 {
+				mantissa, exp, hasExp = mantissa[:i], mantissa[i+1:], true
+				if exp != "" && (exp[0] == '-' || exp[0] == '+') {
+					exp = exp[1:]
+				}
+			}
+			val, err := parseHexNumber(s)
+			if err != nil || mantissa == "" || strings.ContainsAny(mantissa, "+-") || (hasExp && exp == "") {
+				return nil, errors.Errorf("invalid hex number '%s'", str)
+			}
+			if neg {
+				val = val.Negate()
+			}
+			return val.Normalize(), nil
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
+		if base != 0 {
+			val, ok := new(big.Int).SetString(s[2:], base)
+			if !ok || s[2] == '-' || s[2] == '+' {
+				return nil, errors.Errorf("invalid base %d number '%s'", base, str)
+			}
+			if neg {
+				val.Neg(val)
+			}
+			return BigInt(val).Normalize(), nil
+		}
+	}
+
+	if !decimalLiteral.MatchString(str) {
+		return nil, errors.Errorf("invalid number '%s'", str)
+	}
+
+	if strings.ContainsAny(str, ".eE") {
+		dec, err := decimal.NewFromString(str)
+		if err != nil {
+			return nil, errors.Errorf("invalid decimal number '%s', %v", str, err)
+		}
+		return Decimal(dec), nil
+	}
+
+	if long, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return Long(long), nil
+	}
+
+	val, ok := new(big.Int).SetString(str, 10)
+	if !ok {
+		return nil, errors.Errorf("invalid integer number '%s'", str)
+	}
+	return BigInt(val), nil
+}
+
 func parseHexNumber(s string) (Number, error) {
 	neg := false
 	if len(s) >= 1 && s[0] == '-' {
@@ -313,19 +408,35 @@ func (n decimalNumber) Object() interface{} {
 }
 
 func (n longNumber) Pack(p Packer) {
-	p.PackLong(int64(n))
+	packNumber(n, p)
 }
 
 func (n doubleNumber) Pack(p Packer) {
-	p.PackDouble(float64(n))
+	packNumber(n, p)
 }
 
 func (n bigIntNumber) Pack(p Packer) {
+	packNumber(n, p)
+}
+
+func (n decimalNumber) Pack(p Packer) {
+	packNumber(n, p)
+}
+
+func (n longNumber) packRaw(p Packer) {
+	p.PackLong(int64(n))
+}
+
+func (n doubleNumber) packRaw(p Packer) {
+	p.PackDouble(float64(n))
+}
+
+func (n bigIntNumber) packRaw(p Packer) {
 	b, _ := n.Int.GobEncode()
 	p.PackExt(BigIntExt, b)
 }
 
-func (n decimalNumber) Pack(p Packer) {
+func (n decimalNumber) packRaw(p Packer) {
 	dec := decimal.Decimal(n)
 	b, _ := dec.MarshalBinary()
 	p.PackExt(DecimalExt, b)
@@ -517,85 +628,220 @@ func (n decimalNumber) Decimal() decimal.Decimal {
 }
 
 func (n longNumber) Add(other Number) Number {
-	return Long(n.Long() + other.Long())
+	return add(n, other)
 }
 
 func (n doubleNumber) Add(other Number) Number {
-	left := float64(n)
-	right := other.Double()
-	if math.IsNaN(left) || math.IsNaN(right) {
-		return Nan
-	}
-	return Double(left + right)
+	return add(n, other)
 }
 
 func (n bigIntNumber) Add(other Number) Number {
-	z := new(big.Int)
-	z.Add(n.Int, other.BigInt())
-	return BigInt(z)
+	return add(n, other)
 }
 
 func (n decimalNumber) Add(other Number) Number {
-	dec := decimal.Decimal(n)
-	return Decimal(dec.Add(other.Decimal()))
+	return add(n, other)
 }
 
 func (n longNumber) Subtract(other Number) Number {
-	return Long(n.Long() - other.Long())
+	return subtract(n, other)
 }
 
 func (n doubleNumber) Subtract(other Number) Number {
-	left := float64(n)
-	right := other.Double()
-	if math.IsNaN(left) || math.IsNaN(right) {
-		return Nan
-	}
-	return Double(left - right)
+	return subtract(n, other)
 }
 
 func (n bigIntNumber) Subtract(other Number) Number {
-	z := new(big.Int)
-	z.Sub(n.Int, other.BigInt())
-	return BigInt(z)
+	return subtract(n, other)
 }
 
 func (n decimalNumber) Subtract(other Number) Number {
-	dec := decimal.Decimal(n)
-	return Decimal(dec.Sub(other.Decimal()))
+	return subtract(n, other)
+}
+
+func (n longNumber) Multiply(other Number) Number {
+	return multiply(n, other)
+}
+
+func (n doubleNumber) Multiply(other Number) Number {
+	return multiply(n, other)
+}
+
+func (n bigIntNumber) Multiply(other Number) Number {
+	return multiply(n, other)
+}
+
+func (n decimalNumber) Multiply(other Number) Number {
+	return multiply(n, other)
+}
+
+func (n longNumber) Divide(other Number) Number {
+	return divide(n, other)
+}
+
+func (n doubleNumber) Divide(other Number) Number {
+	return divide(n, other)
+}
+
+func (n bigIntNumber) Divide(other Number) Number {
+	return divide(n, other)
+}
+
+func (n decimalNumber) Divide(other Number) Number {
+	return divide(n, other)
+}
+
+func (n longNumber) Mod(other Number) Number {
+	return mod(n, other)
+}
+
+func (n doubleNumber) Mod(other Number) Number {
+	return mod(n, other)
+}
+
+func (n bigIntNumber) Mod(other Number) Number {
+	return mod(n, other)
+}
+
+func (n decimalNumber) Mod(other Number) Number {
+	return mod(n, other)
+}
+
+func (n longNumber) DivideRound(other Number, precision int32) Number {
+	return divideRound(n, other, precision)
+}
+
+func (n doubleNumber) DivideRound(other Number, precision int32) Number {
+	return divideRound(n, other, precision)
+}
+
+func (n bigIntNumber) DivideRound(other Number, precision int32) Number {
+	return divideRound(n, other, precision)
+}
+
+func (n decimalNumber) DivideRound(other Number, precision int32) Number {
+	return divideRound(n, other, precision)
+}
+
+func (n longNumber) Negate() Number {
+	return negateLong(int64(n))
+}
+
+func (n doubleNumber) Negate() Number {
+	return Double(-float64(n))
+}
+
+func (n bigIntNumber) Negate() Number {
+	return BigInt(new(big.Int).Neg(n.Int))
+}
+
+func (n decimalNumber) Negate() Number {
+	return Decimal(decimal.Decimal(n).Neg())
+}
+
+func (n longNumber) Abs() Number {
+	if n < 0 {
+		return negateLong(int64(n))
+	}
+	return n
+}
+
+func (n doubleNumber) Abs() Number {
+	return Double(math.Abs(float64(n)))
+}
+
+func (n bigIntNumber) Abs() Number {
+	return BigInt(new(big.Int).Abs(n.Int))
+}
+
+func (n decimalNumber) Abs() Number {
+	return Decimal(decimal.Decimal(n).Abs())
+}
+
+func (n longNumber) Sign() int {
+	return compareLong(int64(n), 0)
+}
+
+func (n doubleNumber) Sign() int {
+	d := float64(n)
+	switch {
+	case d < 0:
+		return -1
+	case d > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (n bigIntNumber) Sign() int {
+	return n.Int.Sign()
+}
+
+func (n decimalNumber) Sign() int {
+	return decimal.Decimal(n).Sign()
+}
+
+func (n longNumber) Normalize() Number {
+	return n
+}
+
+func (n doubleNumber) Normalize() Number {
+	return n
+}
+
+func (n bigIntNumber) Normalize() Number {
+	if n.Int.IsInt64() {
+		return Long(n.Int.Int64())
+	}
+	return n
+}
+
+func (n decimalNumber) Normalize() Number {
+	return n
+}
+
+func (n longNumber) Compare(other Number) int {
+	return compareNumbers(n, other)
+}
+
+func (n doubleNumber) Compare(other Number) int {
+	return compareNumbers(n, other)
+}
+
+func (n bigIntNumber) Compare(other Number) int {
+	return compareNumbers(n, other)
+}
+
+func (n decimalNumber) Compare(other Number) int {
+	return compareNumbers(n, other)
 }
 
 func (n longNumber) Equal(val Value) bool {
 	if val == nil || val.Kind() != NUMBER {
 		return false
 	}
-	other := val.(Number)
-	return n.Long() == other.Long()
+	return NumberEqual(n, val.(Number), ExactEquality)
 }
 
 func (n doubleNumber) Equal(val Value) bool {
 	if val == nil || val.Kind() != NUMBER {
 		return false
 	}
-	other := val.(Number)
-	if n.IsNaN() || other.IsNaN() {
-		return false
-	}
-	return  math.Abs(n.Double() - other.Double()) < PrecisionLevel
+	return NumberEqual(n, val.(Number), ExactEquality)
 }
 
 func (n bigIntNumber) Equal(val Value) bool {
 	if val == nil || val.Kind() != NUMBER {
 		return false
 	}
-	other := val.(Number)
-	return n.Int.Cmp(other.BigInt()) == 0
+	return NumberEqual(n, val.(Number), ExactEquality)
 }
 
 func (n decimalNumber) Equal(val Value) bool {
 	if val == nil || val.Kind() != NUMBER {
 		return false
 	}
-	other := val.(Number)
-	return n.Decimal().Cmp(other.Decimal()) == 0
+	return NumberEqual(n, val.(Number), ExactEquality)
 }
 
@@ -12,7 +12,9 @@ import (
 	val "github.com/codeallergy/value"
 	"github.com/stretchr/testify/require"
 	"math"
+	"math/big"
 	"encoding/json"
+	"github.com/shopspring/decimal"
 )
 
 
@@ -450,4 +452,290 @@ func TestPackDouble(t *testing.T) {
 
 	}
 
-}
\ No newline at end of file
+}
+func TestNumberPromotion(t *testing.T) {
+
+	long := val.Long(6)
+	double := val.Double(6)
+	bigInt := val.BigInt(big.NewInt(6))
+	dec := val.Decimal(decimal.NewFromInt(6))
+
+	// result type is the widest one: LONG < BIGINT < DECIMAL < DOUBLE
+	promotion := []struct {
+		left, right  val.Number
+		expected     val.NumberType
+	} {
+		{long, long, val.LONG},
+		{long, bigInt, val.BIGINT},
+		{bigInt, long, val.BIGINT},
+		{long, dec, val.DECIMAL},
+		{bigInt, dec, val.DECIMAL},
+		{dec, bigInt, val.DECIMAL},
+		{long, double, val.DOUBLE},
+		{double, long, val.DOUBLE},
+		{bigInt, double, val.DOUBLE},
+		{dec, double, val.DOUBLE},
+	}
+
+	for _, c := range promotion {
+		for _, result := range []val.Number {
+			c.left.Add(c.right),
+			c.left.Subtract(c.right),
+			c.left.Multiply(c.right),
+			c.left.Divide(c.right),
+			c.left.Mod(c.right),
+		} {
+			require.Equal(t, c.expected, result.Type(), "%v and %v", c.left.Type(), c.right.Type())
+		}
+		require.Equal(t, int64(12), c.left.Add(c.right).Long())
+		require.Equal(t, int64(0), c.left.Subtract(c.right).Long())
+		require.Equal(t, int64(36), c.left.Multiply(c.right).Long())
+		require.Equal(t, int64(1), c.left.Divide(c.right).Long())
+		require.Equal(t, int64(0), c.left.Mod(c.right).Long())
+	}
+
+}
+
+func TestMultiplyDivideNumber(t *testing.T) {
+
+	require.Equal(t, int64(-21), val.Long(7).Multiply(val.Long(-3)).Long())
+	require.Equal(t, int64(-2), val.Long(7).Divide(val.Long(-3)).Long())
+	require.Equal(t, int64(1), val.Long(7).Mod(val.Long(-3)).Long())
+	require.Equal(t, int64(-1), val.Long(-7).Mod(val.Long(3)).Long())
+
+	DoubleEqual(t, 2.5, val.Double(7.5).Divide(val.Long(3)).Double())
+	DoubleEqual(t, 1.5, val.Double(7.5).Mod(val.Long(3)).Double())
+
+	price := val.Decimal(decimal.RequireFromString("19.99"))
+	require.Equal(t, "59.97", price.Multiply(val.Long(3)).Decimal().String())
+	require.Equal(t, "6.6633333333333333", price.Divide(val.Long(3)).Decimal().String())
+	require.Equal(t, "1.99", price.Mod(val.Long(3)).Decimal().String())
+
+	q := price.DivideRound(val.Long(3), 2)
+	require.Equal(t, val.DECIMAL, q.Type())
+	require.Equal(t, "6.66", q.Decimal().String())
+
+	q = val.Long(2).DivideRound(val.Long(3), 3)
+	require.Equal(t, val.DECIMAL, q.Type())
+	require.Equal(t, "0.667", q.Decimal().String())
+
+	q = val.Double(2).DivideRound(val.Long(3), 3)
+	require.Equal(t, val.DOUBLE, q.Type())
+	require.Equal(t, 0.667, q.Double())
+
+	huge := val.ParseNumber("0x0100000000000000000000")
+	require.Equal(t, "0x010000000000000000000000", huge.Multiply(val.Long(256)).String())
+	require.Equal(t, "0x01000000000000000000", huge.Divide(val.Long(256)).String())
+
+}
+
+func TestDivideByZero(t *testing.T) {
+
+	require.True(t, val.Long(1).Divide(val.Long(0)).IsNaN())
+	require.True(t, val.Long(1).Mod(val.Long(0)).IsNaN())
+	require.True(t, val.ParseNumber("0x7b").Divide(val.Long(0)).IsNaN())
+	require.True(t, val.Decimal(decimal.NewFromInt(1)).Divide(val.Long(0)).IsNaN())
+	require.True(t, val.Decimal(decimal.NewFromInt(1)).DivideRound(val.Long(0), 2).IsNaN())
+
+	// double follows IEEE 754
+	require.True(t, math.IsInf(val.Double(1).Divide(val.Long(0)).Double(), 1))
+	require.True(t, val.Double(0).Divide(val.Long(0)).IsNaN())
+
+}
+
+func TestNegateAbsSign(t *testing.T) {
+
+	numbers := []val.Number {
+		val.Long(-5),
+		val.Double(-5),
+		val.BigInt(big.NewInt(-5)),
+		val.Decimal(decimal.NewFromInt(-5)),
+	}
+
+	for _, n := range numbers {
+		require.Equal(t, -1, n.Sign())
+		require.Equal(t, n.Type(), n.Negate().Type())
+		require.Equal(t, int64(5), n.Negate().Long())
+		require.Equal(t, 1, n.Negate().Sign())
+		require.Equal(t, n.Type(), n.Abs().Type())
+		require.Equal(t, int64(5), n.Abs().Long())
+	}
+
+	require.Equal(t, 0, val.Zero.Sign())
+	require.Equal(t, 0, val.Nan.Sign())
+
+}
+
+func TestCompareNumber(t *testing.T) {
+
+	ordered := []val.Number {
+		val.Nan,
+		val.Double(math.Inf(-1)),
+		val.ParseNumber("-0x010000000000000000000000"),
+		val.Long(math.MinInt64),
+		val.Decimal(decimal.RequireFromString("-0.5")),
+		val.Long(0),
+		val.Double(0.1),
+		val.Decimal(decimal.RequireFromString("0.1000000000000001")),
+		val.BigInt(big.NewInt(1)),
+		val.Double(math.MaxInt64),
+		val.ParseNumber("0x010000000000000000000000"),
+		val.Double(math.Inf(1)),
+	}
+
+	for i, left := range ordered {
+		for j, right := range ordered {
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			require.Equal(t, expected, left.Compare(right), "%v and %v", left, right)
+		}
+	}
+
+	require.Equal(t, 0, val.Long(1).Compare(val.Double(1)))
+	require.Equal(t, 0, val.Decimal(decimal.RequireFromString("1.000")).Compare(val.BigInt(big.NewInt(1))))
+	require.Equal(t, 0, val.Decimal(decimal.RequireFromString("0.5")).Compare(val.Double(0.5)))
+
+}
+
+func TestLongOverflow(t *testing.T) {
+
+	max := val.Long(math.MaxInt64)
+	min := val.Long(math.MinInt64)
+
+	sum := max.Add(val.One)
+	require.Equal(t, val.BIGINT, sum.Type())
+	require.Equal(t, "9223372036854775808", sum.BigInt().String())
+
+	diff := min.Subtract(val.One)
+	require.Equal(t, val.BIGINT, diff.Type())
+	require.Equal(t, "-9223372036854775809", diff.BigInt().String())
+
+	product := max.Multiply(val.Long(2))
+	require.Equal(t, val.BIGINT, product.Type())
+	require.Equal(t, "18446744073709551614", product.BigInt().String())
+
+	require.Equal(t, val.BIGINT, min.Multiply(val.Long(-1)).Type())
+	require.Equal(t, val.BIGINT, val.Long(-1).Multiply(min).Type())
+	require.Equal(t, val.BIGINT, min.Divide(val.Long(-1)).Type())
+	require.Equal(t, val.BIGINT, min.Negate().Type())
+	require.Equal(t, val.BIGINT, min.Abs().Type())
+	require.Equal(t, "9223372036854775808", min.Abs().BigInt().String())
+
+	// no promotion in range
+	require.Equal(t, val.LONG, max.Subtract(val.One).Type())
+	require.Equal(t, val.LONG, min.Add(val.One).Type())
+	require.Equal(t, val.LONG, val.Long(math.MaxInt32).Multiply(val.Long(math.MaxInt32)).Type())
+	require.Equal(t, val.LONG, val.Long(-3037000499).Multiply(val.Long(3037000499)).Type())
+	require.Equal(t, val.LONG, min.Add(max).Type())
+
+}
+
+func TestNormalize(t *testing.T) {
+
+	balance := val.Long(math.MaxInt64).Add(val.Long(10))
+	require.Equal(t, val.BIGINT, balance.Type())
+	require.Equal(t, val.BIGINT, balance.Normalize().Type())
+
+	balance = balance.Subtract(val.Long(20))
+	require.Equal(t, val.BIGINT, balance.Type())
+
+	n := balance.Normalize()
+	require.Equal(t, val.LONG, n.Type())
+	require.Equal(t, int64(math.MaxInt64 - 10), n.Long())
+	require.Equal(t, "cf7ffffffffffffff5", val.Hex(n))
+
+	require.Equal(t, val.DOUBLE, val.Double(1).Normalize().Type())
+	require.Equal(t, val.DECIMAL, val.Decimal(decimal.NewFromInt(1)).Normalize().Type())
+
+}
+
+func TestParseNumberExact(t *testing.T) {
+
+	n, err := val.ParseNumberExact("123")
+	require.NoError(t, err)
+	require.Equal(t, val.LONG, n.Type())
+	require.Equal(t, int64(123), n.Long())
+
+	n, err = val.ParseNumberExact("-9223372036854775808")
+	require.NoError(t, err)
+	require.Equal(t, val.LONG, n.Type())
+
+	n, err = val.ParseNumberExact("123456789012345678901234567890")
+	require.NoError(t, err)
+	require.Equal(t, val.BIGINT, n.Type())
+	require.Equal(t, "123456789012345678901234567890", n.BigInt().String())
+
+	n, err = val.ParseNumberExact("0.1000000000000000000001")
+	require.NoError(t, err)
+	require.Equal(t, val.DECIMAL, n.Type())
+	require.Equal(t, "0.1000000000000000000001", n.Decimal().String())
+
+	n, err = val.ParseNumberExact("-12.50")
+	require.NoError(t, err)
+	require.Equal(t, val.DECIMAL, n.Type())
+	require.Equal(t, int32(-2), n.Decimal().Exponent())
+
+	n, err = val.ParseNumberExact("1.5e3")
+	require.NoError(t, err)
+	require.Equal(t, val.DECIMAL, n.Type())
+	require.Equal(t, "1500", n.Decimal().String())
+
+	n, err = val.ParseNumberExact("0xff")
+	require.NoError(t, err)
+	require.Equal(t, val.LONG, n.Type())
+	require.Equal(t, int64(255), n.Long())
+
+	n, err = val.ParseNumberExact("+0xff")
+	require.NoError(t, err)
+	require.Equal(t, int64(255), n.Long())
+
+	n, err = val.ParseNumberExact("-0x8000000000000000")
+	require.NoError(t, err)
+	require.Equal(t, val.LONG, n.Type())
+	require.Equal(t, int64(math.MinInt64), n.Long())
+
+	n, err = val.ParseNumberExact("0x01e2afx-03")
+	require.NoError(t, err)
+	require.Equal(t, val.DECIMAL, n.Type())
+	require.Equal(t, "123.567", n.Decimal().String())
+
+	n, err = val.ParseNumberExact("-0x01e2afx-03")
+	require.NoError(t, err)
+	require.Equal(t, "-123.567", n.Decimal().String())
+
+	// own format of Decimal.String
+	price := val.Decimal(decimal.RequireFromString("-19.99"))
+	n, err = val.ParseNumberExact(price.String())
+	require.NoError(t, err)
+	require.True(t, price.Equal(n))
+
+	n, err = val.ParseNumberExact("-0b101")
+	require.NoError(t, err)
+	require.Equal(t, int64(-5), n.Long())
+
+	n, err = val.ParseNumberExact("0o755")
+	require.NoError(t, err)
+	require.Equal(t, int64(493), n.Long())
+
+	n, err = val.ParseNumberExact("0o7777777777777777777777777")
+	require.NoError(t, err)
+	require.Equal(t, val.BIGINT, n.Type())
+
+	n, err = val.ParseNumberExact("NaN")
+	require.NoError(t, err)
+	require.True(t, math.IsNaN(n.Double()))
+
+	n, err = val.ParseNumberExact("-Inf")
+	require.NoError(t, err)
+	require.True(t, math.IsInf(n.Double(), -1))
+
+	for _, s := range []string { "", "abc", "1.2.3", "1e", "--1", "0b102", "0x", "0o8", "1_000", " 1", "0x-1", "0x+1x2", "0x-1x2", "+-0xff", "0x10x", "0x10x-", "0x10x+" } {
+		_, err = val.ParseNumberExact(s)
+		require.Error(t, err, s)
+	}
+
+}
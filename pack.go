@@ -17,6 +17,8 @@ const (
 
 	BigIntExt
 	DecimalExt
+	EncryptedExt
+	LinkExt
 
 	MaxExt
 )
@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/nacl/box"
+	"io"
+)
+
+/**
+	Multi-recipient sealed format
+
+	[magic "VALM"][version][uint16 count][key slot]...[nonce 24][secretbox]
+
+	Payload is sealed once by secretbox with the random content key, every key slot is the content key
+	sealed anonymously for one recipient, slots do not reveal recipients and are tried in order
+*/
+
+const (
+	MultiVersion byte = 1
+
+	multiHeaderSize = 7
+	multiSlotSize   = 32 + box.AnonymousOverhead
+)
+
+var MultiMagic = []byte("VALM")
+
+/**
+	Seals value for the recipient without sender key, the recipient can not authenticate the sender
+*/
+
+func SealAnonymous(val Value, recipientPublicKey *[32]byte) ([]byte, error) {
+	unencrypted, err := Pack(val)
+	if err != nil {
+		return nil, err
+	}
+	return box.SealAnonymous(nil, unencrypted, recipientPublicKey, rand.Reader)
+}
+
+func UnsealAnonymous(encrypted []byte, recipientPublicKey, recipientPrivateKey *[32]byte) (Value, error) {
+	decrypted, ok := box.OpenAnonymous(nil, encrypted, recipientPublicKey, recipientPrivateKey)
+	if !ok {
+		return nil, ErrUnseal
+	}
+	return Unpack(decrypted, false)
+}
+
+/**
+	Seals value for all recipients, size of the result grows by 80 bytes per recipient
+*/
+
+func SealMulti(val Value, recipients [][32]byte) ([]byte, error) {
+
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+	if len(recipients) > 0xffff {
+		return nil, errors.Errorf("too many recipients %d", len(recipients))
+	}
+
+	unencrypted, err := Pack(val)
+	if err != nil {
+		return nil, err
+	}
+
+	var contentKey [32]byte
+	if _, err := io.ReadFull(rand.Reader, contentKey[:]); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, multiHeaderSize, multiHeaderSize + len(recipients) * multiSlotSize + secretNonceSize + len(unencrypted) + box.Overhead)
+	copy(out, MultiMagic)
+	out[4] = MultiVersion
+	binary.BigEndian.PutUint16(out[5:], uint16(len(recipients)))
+
+	for i := range recipients {
+		out, err = box.SealAnonymous(out, contentKey[:], &recipients[i], rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sealSecret(out, unencrypted, &contentKey)
+}
+
+/**
+	Unseals value sealed by SealMulti with the key pair of any recipient, returns ErrUnseal if there is no slot for it
+*/
+
+func UnsealMulti(encrypted []byte, recipientPublicKey, recipientPrivateKey *[32]byte) (Value, error) {
+
+	if len(encrypted) < multiHeaderSize || !bytes.Equal(encrypted[:4], MultiMagic) {
+		return nil, errors.Wrap(ErrUnseal, "not a multi-recipient sealed value")
+	}
+	if encrypted[4] != MultiVersion {
+		return nil, errors.Wrapf(ErrUnseal, "unsupported multi-recipient version %d", encrypted[4])
+	}
+	cnt := int(binary.BigEndian.Uint16(encrypted[5:]))
+	body := multiHeaderSize + cnt * multiSlotSize
+	if len(encrypted) < body {
+		return nil, errors.Wrap(ErrUnseal, "truncated key slots")
+	}
+
+	for i := 0; i < cnt; i++ {
+		off := multiHeaderSize + i * multiSlotSize
+		key, ok := box.OpenAnonymous(nil, encrypted[off:off+multiSlotSize], recipientPublicKey, recipientPrivateKey)
+		if !ok || len(key) != 32 {
+			continue
+		}
+		var contentKey [32]byte
+		copy(contentKey[:], key)
+		return UnsealSecret(encrypted[body:], &contentKey)
+	}
+
+	return nil, ErrUnseal
+}
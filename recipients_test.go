@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"crypto/rand"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/nacl/box"
+)
+
+func TestSealAnonymous(t *testing.T) {
+
+	pub, priv, err := box.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherPub, otherPriv, err := box.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	upload := val.Tuple(val.Utf8("report"), val.Long(42))
+
+	sealed, err := val.SealAnonymous(upload, pub)
+	require.NoError(t, err)
+
+	v, err := val.UnsealAnonymous(sealed, pub, priv)
+	require.NoError(t, err)
+	require.True(t, upload.Equal(v))
+
+	_, err = val.UnsealAnonymous(sealed, otherPub, otherPriv)
+	require.Equal(t, val.ErrUnseal, err)
+
+}
+
+func TestSealMulti(t *testing.T) {
+
+	type keyPair struct {
+		pub, priv *[32]byte
+	}
+
+	var pairs []keyPair
+	var recipients [][32]byte
+	for i := 0; i < 3; i++ {
+		pub, priv, err := box.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		pairs = append(pairs, keyPair{pub, priv})
+		recipients = append(recipients, *pub)
+	}
+
+	doc := val.EmptyImmutableMap().Put("msg", val.Utf8("hello all"))
+
+	sealed, err := val.SealMulti(doc, recipients)
+	require.NoError(t, err)
+
+	for _, p := range pairs {
+		v, err := val.UnsealMulti(sealed, p.pub, p.priv)
+		require.NoError(t, err)
+		require.True(t, doc.Equal(v))
+	}
+
+	strangerPub, strangerPriv, err := box.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = val.UnsealMulti(sealed, strangerPub, strangerPriv)
+	require.Equal(t, val.ErrUnseal, err)
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered) - 1]++
+	_, err = val.UnsealMulti(tampered, pairs[0].pub, pairs[0].priv)
+	require.Equal(t, val.ErrUnseal, err)
+
+	_, err = val.UnsealMulti(sealed[:20], pairs[0].pub, pairs[0].priv)
+	require.True(t, errors.Is(err, val.ErrUnseal))
+
+	_, err = val.SealMulti(doc, nil)
+	require.Error(t, err)
+
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/**
+	Marker that replaces redacted values by default
+*/
+
+var RedactMarker = "***"
+
+/**
+	Rules of redaction
+
+	Keys are glob patterns of path.Match applied to map keys case-insensitively, like "password" or "*token*".
+	Paths are dot separated keys and list indexes from the root, '*' segment matches any key, like "users.*.email".
+	Redacted value is replaced by Marker or RedactMarker, if HashKey is set by "hmac:" and the first 16 hex digits
+	of HMAC-SHA256 over the packed value, so equal secrets can be correlated in logs without revealing them.
+*/
+
+type RedactRules struct {
+	Keys     []string
+	Paths    []string
+	Marker   string
+	HashKey  []byte
+}
+
+/**
+	Returns copy of the value with redacted entries, the original value is not changed
+
+	Redacted containers are rebuilt as immutable ones, unchanged subtrees are shared with the original value
+*/
+
+func Redact(val Value, rules *RedactRules) Value {
+	if val == nil || rules == nil {
+		return val
+	}
+	r := &redactor{rules: rules}
+	for _, p := range rules.Paths {
+		r.paths = append(r.paths, strings.Split(p, "."))
+	}
+	for _, k := range rules.Keys {
+		r.keys = append(r.keys, strings.ToLower(k))
+	}
+	res, _ := r.redact(val, nil)
+	return res
+}
+
+/**
+	Prints value as JSON with redaction
+*/
+
+func PrintJSONWithOptions(out *strings.Builder, val Value, rules *RedactRules) {
+	val = Redact(val, rules)
+	if val != nil {
+		val.PrintJSON(out)
+	} else {
+		out.WriteString("null")
+	}
+}
+
+/**
+	Same as Jsonify with redaction, use for logging
+*/
+
+func JsonifyRedacted(val Value, rules *RedactRules) string {
+	var out strings.Builder
+	PrintJSONWithOptions(&out, val, rules)
+	return out.String()
+}
+
+/**
+	Redacts value of the tagged struct, fields with `sensitive:"true"` tag are redacted in addition to the rules
+
+	Struct is packed as by PackStruct, so map keys and paths are field tag numbers
+*/
+
+func RedactStruct(obj interface{}, rules *RedactRules) (Value, error) {
+	blob, err := PackStruct(obj)
+	if err != nil {
+		return nil, err
+	}
+	val, err := Unpack(blob, false)
+	if err != nil {
+		return nil, err
+	}
+	var all RedactRules
+	if rules != nil {
+		all = *rules
+	}
+	all.Paths = append([]string(nil), all.Paths...)
+	if classPtr := reflect.TypeOf(obj); classPtr != nil && classPtr.Kind() == reflect.Ptr {
+		if schema, err := reflectSchema(classPtr); err == nil {
+			all.Paths = appendSensitivePaths(all.Paths, "", schema)
+		}
+	}
+	return Redact(val, &all), nil
+}
+
+func appendSensitivePaths(paths []string, prefix string, schema *Schema) []string {
+	for _, field := range schema.SortedFields {
+		p := prefix + strconv.Itoa(field.Tag)
+		if field.Sensitive {
+			paths = append(paths, p)
+		} else if field.Struct {
+			if field.Array && !field.Repeated {
+				p += ".*"
+			}
+			paths = appendSensitivePaths(paths, p + ".", field.FieldSchema)
+		}
+	}
+	return paths
+}
+
+type redactor struct {
+	rules  *RedactRules
+	keys   []string
+	paths  [][]string
+}
+
+func (r *redactor) matchKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range r.keys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) matchPath(p []string) bool {
+	for _, pattern := range r.paths {
+		if len(pattern) != len(p) {
+			continue
+		}
+		matched := true
+		for i, seg := range pattern {
+			if seg != "*" && seg != p[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) mask(val Value) Value {
+	if len(r.rules.HashKey) > 0 {
+		mac := hmac.New(sha256.New, r.rules.HashKey)
+		b, _ := Pack(val)
+		mac.Write(b)
+		return Utf8("hmac:" + hex.EncodeToString(mac.Sum(nil))[:16])
+	}
+	if r.rules.Marker != "" {
+		return Utf8(r.rules.Marker)
+	}
+	return Utf8(RedactMarker)
+}
+
+/**
+	Returns redacted value and true if anything was changed
+*/
+
+func (r *redactor) redact(val Value, p []string) (Value, bool) {
+
+	if val == nil {
+		return nil, false
+	}
+
+	child := func(key string, key2 string, el Value) (Value, bool) {
+		cp := append(p[:len(p):len(p)], key)
+		if (key2 != "" && r.matchKey(key2)) || r.matchPath(cp) {
+			return r.mask(el), true
+		}
+		return r.redact(el, cp)
+	}
+
+	switch val.Kind() {
+
+	case MAP:
+		if vm, ok := val.(immutableValueMap); ok {
+			list := make([]valueMapEntry, len(vm.list))
+			changed := false
+			for i, e := range vm.list {
+				k := e.key.String()
+				v, c := child(k, k, e.value)
+				list[i] = valueMapEntry{e.key, v}
+				changed = changed || c
+			}
+			if !changed {
+				return val, false
+			}
+			return newImmutableValueMap(list), true
+		}
+		entries := val.(Map).Entries()
+		list := make([]MapEntry, len(entries))
+		changed := false
+		for i, e := range entries {
+			v, c := child(e.Key(), e.Key(), e.Value())
+			list[i] = ImmutableEntry(e.Key(), v)
+			changed = changed || c
+		}
+		if !changed {
+			return val, false
+		}
+		return ImmutableMap(list, true), true
+
+	case LIST:
+		if sparse, ok := val.(sparseListValue); ok {
+			items := make([]ListItem, len(sparse))
+			changed := false
+			for i, item := range sparse {
+				v, c := child(strconv.Itoa(item.Key()), "", item.Value())
+				items[i] = ImmutableItem(item.Key(), v)
+				changed = changed || c
+			}
+			if !changed {
+				return val, false
+			}
+			return SparseList(items, true), true
+		}
+		values := val.(List).Values()
+		list := make([]Value, len(values))
+		changed := false
+		for i, el := range values {
+			v, c := child(strconv.Itoa(i), "", el)
+			list[i] = v
+			changed = changed || c
+		}
+		if !changed {
+			return val, false
+		}
+		return ImmutableList(list), true
+
+	default:
+		return val, false
+	}
+}
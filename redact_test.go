@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"strings"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactKeys(t *testing.T) {
+
+	user := val.EmptyImmutableMap().
+		Put("name", val.Utf8("bob")).
+		Put("Password", val.Utf8("secret")).
+		Put("apiToken", val.Utf8("t0k3n")).
+		Put("sessions", val.Tuple(val.EmptyImmutableMap().Put("refresh_token", val.Utf8("r1"))))
+
+	rules := &val.RedactRules{ Keys: []string{ "password", "*token*" } }
+
+	require.Equal(t, `{"Password": "***","apiToken": "***","name": "bob","sessions": [{"refresh_token": "***"}]}`, val.JsonifyRedacted(user, rules))
+
+	// original is not changed
+	require.Equal(t, "secret", user.Get("Password").String())
+
+	var out strings.Builder
+	val.PrintJSONWithOptions(&out, user, &val.RedactRules{ Keys: []string{ "name" }, Marker: "[hidden]" })
+	require.True(t, strings.Contains(out.String(), `"name": "[hidden]"`))
+
+	// nothing to redact returns the same value
+	require.True(t, user.Equal(val.Redact(user, &val.RedactRules{ Keys: []string{ "email" } })))
+	require.Nil(t, val.Redact(nil, rules))
+
+}
+
+func TestRedactPaths(t *testing.T) {
+
+	doc := val.EmptyImmutableMap().
+		Put("users", val.Tuple(
+			val.EmptyImmutableMap().Put("email", val.Utf8("a@x")).Put("id", val.Long(1)),
+			val.EmptyImmutableMap().Put("email", val.Utf8("b@x")).Put("id", val.Long(2)),
+		)).
+		Put("email", val.Utf8("admin@x"))
+
+	redacted := val.Redact(doc, &val.RedactRules{ Paths: []string{ "users.*.email" } })
+	require.Equal(t, `{"email": "admin@x","users": [{"email": "***","id": 1},{"email": "***","id": 2}]}`, val.Jsonify(redacted))
+
+	redacted = val.Redact(doc, &val.RedactRules{ Paths: []string{ "users.1" } })
+	require.Equal(t, `{"email": "admin@x","users": [{"email": "a@x","id": 1},"***"]}`, val.Jsonify(redacted))
+
+}
+
+func TestRedactHash(t *testing.T) {
+
+	rules := &val.RedactRules{ Keys: []string{ "ssn" }, HashKey: []byte("log-key") }
+
+	a := val.Redact(val.EmptyImmutableMap().Put("ssn", val.Utf8("123-45-6789")), rules).(val.Map).Get("ssn").String()
+	b := val.Redact(val.EmptyImmutableMap().Put("ssn", val.Utf8("123-45-6789")), rules).(val.Map).Get("ssn").String()
+	c := val.Redact(val.EmptyImmutableMap().Put("ssn", val.Utf8("987-65-4321")), rules).(val.Map).Get("ssn").String()
+
+	require.True(t, strings.HasPrefix(a, "hmac:"))
+	require.Equal(t, 21, len(a))
+	require.Equal(t, a, b)
+	require.NotEqual(t, a, c)
+	require.False(t, strings.Contains(a, "6789"))
+
+}
+
+type Login struct {
+
+	User      val.String   `tag:"1"`
+	Password  val.String   `tag:"2" sensitive:"true"`
+	Inner     *Secret      `tag:"3"`
+
+}
+
+type Secret struct {
+
+	Note     val.String   `tag:"1"`
+	Pin      val.Number   `tag:"2" sensitive:"true"`
+
+}
+
+func TestRedactStruct(t *testing.T) {
+
+	s := &Login{
+		User: val.Utf8("bob"),
+		Password: val.Utf8("hunter2"),
+		Inner: &Secret{ Note: val.Utf8("hi"), Pin: val.Long(1234) },
+	}
+
+	redacted, err := val.RedactStruct(s, nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"1": "bob","2": "***","3": {"1": "hi","2": "***"}}`, val.Jsonify(redacted))
+	require.Equal(t, "hunter2", s.Password.String())
+
+}
@@ -0,0 +1,296 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+	"io"
+)
+
+/**
+	Password sealed format
+
+	[magic "VALP"][version][kdf][kdf params][salt len][salt][nonce 24][secretbox]
+
+	scrypt params are log2(N) byte, r and p uint32, argon2id params are time and memory in KiB uint32, threads byte,
+	all integers in big endian
+*/
+
+const (
+	PasswordVersion byte = 1
+
+	secretNonceSize = 24
+	passwordSaltSize = 16
+)
+
+var PasswordMagic = []byte("VALP")
+
+type PasswordKDF byte
+
+const (
+	ScryptKDF   PasswordKDF = iota + 1
+	Argon2idKDF
+)
+
+func (k PasswordKDF) String() string {
+	switch k {
+	case ScryptKDF:
+		return "scrypt"
+	case Argon2idKDF:
+		return "argon2id"
+	default:
+		return "unknown"
+	}
+}
+
+/**
+	Key derivation parameters, fields of the other KDF are ignored
+*/
+
+type PasswordParams struct {
+	KDF      PasswordKDF
+
+	LogN     uint8   // scrypt N = 1 << LogN
+	R        uint32  // scrypt
+	P        uint32  // scrypt
+
+	Time     uint32  // argon2id passes
+	Memory   uint32  // argon2id memory in KiB
+	Threads  uint8   // argon2id
+}
+
+/**
+	Argon2id with the parameters recommended by RFC 9106 for memory constrained environments
+*/
+
+var DefaultPasswordParams = PasswordParams {
+	KDF: Argon2idKDF,
+	Time: 3,
+	Memory: 64 * 1024,
+	Threads: 4,
+}
+
+/**
+	Limits parameters accepted by UnsealWithPassword, protects from headers that make derivation too expensive
+*/
+
+var MaxPasswordMemory uint64 = 1 << 30
+
+var MaxPasswordPasses uint32 = 64  // argon2id time and scrypt p
+
+func (p PasswordParams) validate() error {
+	switch p.KDF {
+	case ScryptKDF:
+		if p.LogN == 0 || p.LogN > 31 || p.R == 0 || p.P == 0 {
+			return errors.Errorf("invalid scrypt params N=2^%d r=%d p=%d", p.LogN, p.R, p.P)
+		}
+		// scrypt allocates 128*r*p and 128*r*N bytes, checked by division to not overflow
+		limit := MaxPasswordMemory / 128
+		if uint64(p.R) > limit / uint64(p.P) {
+			return errors.Errorf("scrypt params r=%d p=%d exceed MaxPasswordMemory", p.R, p.P)
+		}
+		if uint64(p.R) > limit >> p.LogN {
+			return errors.Errorf("scrypt params N=2^%d r=%d exceed MaxPasswordMemory", p.LogN, p.R)
+		}
+		if p.P > MaxPasswordPasses {
+			return errors.Errorf("scrypt p %d exceeds MaxPasswordPasses", p.P)
+		}
+	case Argon2idKDF:
+		if p.Time == 0 || p.Memory == 0 || p.Threads == 0 {
+			return errors.Errorf("invalid argon2id params time=%d memory=%d threads=%d", p.Time, p.Memory, p.Threads)
+		}
+		if p.Time > MaxPasswordPasses {
+			return errors.Errorf("argon2id time %d exceeds MaxPasswordPasses", p.Time)
+		}
+		if uint64(p.Memory) * 1024 > MaxPasswordMemory {
+			return errors.Errorf("argon2id memory %d KiB exceeds MaxPasswordMemory", p.Memory)
+		}
+	default:
+		return errors.Errorf("unknown password kdf %d", p.KDF)
+	}
+	return nil
+}
+
+func (p PasswordParams) deriveKey(password, salt []byte) (*[32]byte, error) {
+	var key [32]byte
+	switch p.KDF {
+	case ScryptKDF:
+		k, err := scrypt.Key(password, salt, 1 << p.LogN, int(p.R), int(p.P), len(key))
+		if err != nil {
+			return nil, err
+		}
+		copy(key[:], k)
+	default:
+		copy(key[:], argon2.IDKey(password, salt, p.Time, p.Memory, p.Threads, uint32(len(key))))
+	}
+	return &key, nil
+}
+
+/**
+	Seals value by the symmetric key with secretbox, result is nonce followed by the box
+*/
+
+func SealSecret(val Value, key *[32]byte) ([]byte, error) {
+	unencrypted, err := Pack(val)
+	if err != nil {
+		return nil, err
+	}
+	return sealSecret(nil, unencrypted, key)
+}
+
+func sealSecret(out, unencrypted []byte, key *[32]byte) ([]byte, error) {
+	var nonce [secretNonceSize]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return nil, err
+	}
+	out = append(out, nonce[:]...)
+	return secretbox.Seal(out, unencrypted, &nonce, key), nil
+}
+
+func UnsealSecret(encrypted []byte, key *[32]byte) (Value, error) {
+	if len(encrypted) < secretNonceSize {
+		return nil, ErrUnseal
+	}
+	var nonce [secretNonceSize]byte
+	copy(nonce[:], encrypted)
+	decrypted, ok := secretbox.Open(nil, encrypted[secretNonceSize:], &nonce, key)
+	if !ok {
+		return nil, ErrUnseal
+	}
+	return Unpack(decrypted, false)
+}
+
+/**
+	Seals value by the password with DefaultPasswordParams
+*/
+
+func SealWithPassword(val Value, password []byte) ([]byte, error) {
+	return SealWithPasswordParams(val, password, DefaultPasswordParams)
+}
+
+func SealWithPasswordParams(val Value, password []byte, params PasswordParams) ([]byte, error) {
+
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, passwordSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	key, err := params.deriveKey(password, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	unencrypted, err := Pack(val)
+	if err != nil {
+		return nil, err
+	}
+
+	var header bytes.Buffer
+	header.Write(PasswordMagic)
+	header.WriteByte(PasswordVersion)
+	header.WriteByte(byte(params.KDF))
+	var buf [4]byte
+	writeUint32 := func(v uint32) {
+		binary.BigEndian.PutUint32(buf[:], v)
+		header.Write(buf[:])
+	}
+	switch params.KDF {
+	case ScryptKDF:
+		header.WriteByte(params.LogN)
+		writeUint32(params.R)
+		writeUint32(params.P)
+	default:
+		writeUint32(params.Time)
+		writeUint32(params.Memory)
+		header.WriteByte(params.Threads)
+	}
+	header.WriteByte(byte(len(salt)))
+	header.Write(salt)
+
+	return sealSecret(header.Bytes(), unencrypted, key)
+}
+
+/**
+	Unseals value sealed by SealWithPassword, returns ErrUnseal on the wrong password or corrupted data
+*/
+
+func UnsealWithPassword(encrypted []byte, password []byte) (Value, error) {
+
+	params, salt, rest, err := parsePasswordHeader(encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := params.deriveKey(password, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return UnsealSecret(rest, key)
+}
+
+/**
+	Returns key derivation parameters of the password sealed data
+*/
+
+func PasswordParamsOf(encrypted []byte) (PasswordParams, error) {
+	params, _, _, err := parsePasswordHeader(encrypted)
+	return params, err
+}
+
+func parsePasswordHeader(b []byte) (params PasswordParams, salt []byte, rest []byte, err error) {
+
+	truncated := errors.Wrap(ErrUnseal, "truncated password header")
+
+	if len(b) < len(PasswordMagic) + 2 || !bytes.Equal(b[:len(PasswordMagic)], PasswordMagic) {
+		return params, nil, nil, errors.Wrap(ErrUnseal, "not a password sealed value")
+	}
+	b = b[len(PasswordMagic):]
+	if b[0] != PasswordVersion {
+		return params, nil, nil, errors.Wrapf(ErrUnseal, "unsupported password header version %d", b[0])
+	}
+	params.KDF = PasswordKDF(b[1])
+	b = b[2:]
+
+	switch params.KDF {
+	case ScryptKDF:
+		if len(b) < 9 {
+			return params, nil, nil, truncated
+		}
+		params.LogN = b[0]
+		params.R = binary.BigEndian.Uint32(b[1:])
+		params.P = binary.BigEndian.Uint32(b[5:])
+		b = b[9:]
+	case Argon2idKDF:
+		if len(b) < 9 {
+			return params, nil, nil, truncated
+		}
+		params.Time = binary.BigEndian.Uint32(b)
+		params.Memory = binary.BigEndian.Uint32(b[4:])
+		params.Threads = b[8]
+		b = b[9:]
+	}
+
+	if err := params.validate(); err != nil {
+		return params, nil, nil, errors.Wrap(ErrUnseal, err.Error())
+	}
+
+	if len(b) < 1 || len(b) < 1 + int(b[0]) {
+		return params, nil, nil, truncated
+	}
+	n := int(b[0])
+	return params, b[1:1+n], b[1+n:], nil
+}
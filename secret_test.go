@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testScrypt = val.PasswordParams{ KDF: val.ScryptKDF, LogN: 10, R: 8, P: 1 }
+	testArgon2 = val.PasswordParams{ KDF: val.Argon2idKDF, Time: 1, Memory: 64, Threads: 1 }
+)
+
+func TestSealSecret(t *testing.T) {
+
+	var key, otherKey [32]byte
+	rand.Read(key[:])
+	rand.Read(otherKey[:])
+
+	secret := val.EmptyImmutableMap().Put("token", val.Utf8("abc"))
+
+	sealed, err := val.SealSecret(secret, &key)
+	require.NoError(t, err)
+
+	v, err := val.UnsealSecret(sealed, &key)
+	require.NoError(t, err)
+	require.True(t, secret.Equal(v))
+
+	_, err = val.UnsealSecret(sealed, &otherKey)
+	require.Equal(t, val.ErrUnseal, err)
+
+	_, err = val.UnsealSecret(sealed[:10], &key)
+	require.Equal(t, val.ErrUnseal, err)
+
+}
+
+func TestSealWithPassword(t *testing.T) {
+
+	secret := val.Tuple(val.Utf8("db"), val.Utf8("s3cr3t"))
+	password := []byte("correct horse battery staple")
+
+	for _, params := range []val.PasswordParams { testScrypt, testArgon2 } {
+
+		sealed, err := val.SealWithPasswordParams(secret, password, params)
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(sealed, val.PasswordMagic))
+
+		p, err := val.PasswordParamsOf(sealed)
+		require.NoError(t, err)
+		require.Equal(t, params, p)
+
+		v, err := val.UnsealWithPassword(sealed, password)
+		require.NoError(t, err, params.KDF.String())
+		require.True(t, secret.Equal(v))
+
+		_, err = val.UnsealWithPassword(sealed, []byte("wrong"))
+		require.True(t, errors.Is(err, val.ErrUnseal))
+
+		// corrupted salt derives another key
+		corrupted := append([]byte(nil), sealed...)
+		corrupted[len(val.PasswordMagic) + 13]++
+		_, err = val.UnsealWithPassword(corrupted, password)
+		require.Error(t, err)
+
+	}
+
+	_, err := val.UnsealWithPassword([]byte("VALP"), password)
+	require.True(t, errors.Is(err, val.ErrUnseal))
+
+	_, err = val.SealWithPasswordParams(secret, password, val.PasswordParams{ KDF: val.ScryptKDF })
+	require.Error(t, err)
+
+	huge := val.PasswordParams{ KDF: val.Argon2idKDF, Time: 1, Memory: 1 << 31, Threads: 1 }
+	_, err = val.SealWithPasswordParams(secret, password, huge)
+	require.Error(t, err)
+
+	for _, p := range []val.PasswordParams {
+		{ KDF: val.ScryptKDF, LogN: 1, R: 1, P: 1 << 29 },             // 128*r*p is 64 GiB
+		{ KDF: val.ScryptKDF, LogN: 31, R: 1<<32 - 1, P: 1 },         // 128*r*N overflows uint64
+		{ KDF: val.ScryptKDF, LogN: 1, R: 1<<32 - 1, P: 1<<32 - 1 },  // r*p overflows uint64
+		{ KDF: val.ScryptKDF, LogN: 1, R: 1, P: 1000 },
+	} {
+		_, err = val.SealWithPasswordParams(secret, password, p)
+		require.Error(t, err, "%+v", p)
+	}
+
+	slow := val.PasswordParams{ KDF: val.Argon2idKDF, Time: 1 << 24, Memory: 64, Threads: 1 }
+	_, err = val.SealWithPasswordParams(secret, password, slow)
+	require.Error(t, err)
+
+}
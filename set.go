@@ -0,0 +1,235 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"bytes"
+	"crypto"
+	"reflect"
+	"sort"
+)
+
+/**
+	Set interface
+
+	Set is an immutable list of distinct values sorted by Compare, so equal sets always have the same
+	MessagePack encoding. Numbers are stored in the canonical form, Long(1) and Double(1.0) are the same element,
+	and numbers nested in the elements are packed in the canonical form.
+	Packs as an array, so it is unpacked back as a list, use SetOf to restore the set.
+*/
+
+type Set interface {
+	List
+
+	/**
+	Checks if set has the element
+	*/
+
+	Contains(Value) bool
+
+	/**
+	Returns set with the added element
+	*/
+
+	Add(Value) Set
+
+	/**
+	Returns set without the element
+	*/
+
+	Remove(Value) Set
+
+	/**
+	Returns set with elements of both sets
+	*/
+
+	Union(Set) Set
+
+	/**
+	Returns set with elements that are in both sets
+	*/
+
+	Intersection(Set) Set
+
+	/**
+	Returns set with elements of this set that are not in the other one
+	*/
+
+	Difference(Set) Set
+}
+
+type immutableSetValue struct {
+	immutableListValue
+}
+
+var immutableSetValueClass = reflect.TypeOf((*immutableSetValue)(nil)).Elem()
+
+func EmptySet() Set {
+	return newImmutableSet([]Value{})
+}
+
+/**
+	Creates set from values in any order with duplicates
+*/
+
+func SetOf(values ...Value) Set {
+	list := make([]Value, len(values))
+	for i, val := range values {
+		list[i] = setElement(val)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return Compare(list[i], list[j]) < 0
+	})
+	n := 0
+	for i, val := range list {
+		if i == 0 || Compare(list[n-1], val) != 0 {
+			list[n] = val
+			n++
+		}
+	}
+	return newImmutableSet(list[:n])
+}
+
+func newImmutableSet(list []Value) immutableSetValue {
+	return immutableSetValue{newImmutableList(list)}
+}
+
+func setElement(val Value) Value {
+	if val == nil {
+		return Null
+	}
+	if val.Kind() == NUMBER {
+		return Canonical(val.(Number))
+	}
+	return val
+}
+
+func (t immutableSetValue) Class() reflect.Type {
+	return immutableSetValueClass
+}
+
+/**
+	Packs elements with canonical numbers, so Tuple(Long(1)) and Tuple(Double(1)) elements have the same encoding
+*/
+
+func (t immutableSetValue) Pack(p Packer) {
+	if mp, ok := p.(*messagePacker); ok {
+		if !mp.canonical {
+			mp.canonical = true
+			defer func() { mp.canonical = false }()
+		}
+	} else {
+		p = canonicalNumbersPacker{p}
+	}
+	t.immutableListValue.Pack(p)
+}
+
+func (t immutableSetValue) MarshalBinary() ([]byte, error) {
+	buf := bytes.Buffer{}
+	p := MessagePacker(&buf)
+	t.Pack(p)
+	return buf.Bytes(), p.Error()
+}
+
+func (t immutableSetValue) digest(hash crypto.Hash, canonical bool) ([]byte, []byte) {
+	return t.memo.load().digest(hash, true, t.immutableChildren, t.pack)
+}
+
+type canonicalNumbersPacker struct {
+	Packer
+}
+
+func (p canonicalNumbersPacker) canonicalNumbers() bool {
+	return true
+}
+
+func (t immutableSetValue) search(val Value) (int, bool) {
+	i := sort.Search(len(t.list), func(i int) bool {
+		return Compare(t.list[i], val) >= 0
+	})
+	return i, i < len(t.list) && Compare(t.list[i], val) == 0
+}
+
+func (t immutableSetValue) Contains(val Value) bool {
+	_, ok := t.search(setElement(val))
+	return ok
+}
+
+func (t immutableSetValue) Add(val Value) Set {
+	val = setElement(val)
+	i, ok := t.search(val)
+	if ok {
+		return t
+	}
+	dst := make([]Value, len(t.list)+1)
+	copy(dst, t.list[:i])
+	dst[i] = val
+	copy(dst[i+1:], t.list[i:])
+	return newImmutableSet(dst)
+}
+
+func (t immutableSetValue) Remove(val Value) Set {
+	i, ok := t.search(setElement(val))
+	if !ok {
+		return t
+	}
+	dst := make([]Value, len(t.list)-1)
+	copy(dst, t.list[:i])
+	copy(dst[i:], t.list[i+1:])
+	return newImmutableSet(dst)
+}
+
+func (t immutableSetValue) Union(other Set) Set {
+	return t.merge(other, true, true, true)
+}
+
+func (t immutableSetValue) Intersection(other Set) Set {
+	return t.merge(other, false, true, false)
+}
+
+func (t immutableSetValue) Difference(other Set) Set {
+	return t.merge(other, true, false, false)
+}
+
+/**
+	Merges two sorted lists, flags select elements only in this set, in both sets and only in the other set
+*/
+
+func (t immutableSetValue) merge(other Set, left, both, right bool) Set {
+	a, b := t.list, other.Values()
+	var dst []Value
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch c := Compare(a[i], b[j]); {
+		case c < 0:
+			if left {
+				dst = append(dst, a[i])
+			}
+			i++
+		case c > 0:
+			if right {
+				dst = append(dst, b[j])
+			}
+			j++
+		default:
+			if both {
+				dst = append(dst, a[i])
+			}
+			i++
+			j++
+		}
+	}
+	if left {
+		dst = append(dst, a[i:]...)
+	}
+	if right {
+		dst = append(dst, b[j:]...)
+	}
+	if dst == nil {
+		dst = []Value{}
+	}
+	return newImmutableSet(dst)
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet(t *testing.T) {
+
+	s := val.SetOf(val.Utf8("b"), val.Long(2), val.Utf8("a"), val.Double(2), val.Null)
+	require.Equal(t, val.LIST, s.Kind())
+	require.Equal(t, "value.immutableSetValue", s.Class().String())
+	require.Equal(t, 4, s.Len())
+	require.Equal(t, `[null,2,"a","b"]`, val.Jsonify(s))
+
+	require.True(t, s.Contains(val.Long(2)))
+	require.True(t, s.Contains(val.Double(2)))
+	require.True(t, s.Contains(nil))
+	require.False(t, s.Contains(val.Utf8("c")))
+
+	s2 := s.Add(val.Utf8("c")).Add(val.Utf8("a")).Remove(val.Null)
+	require.Equal(t, `[2,"a","b","c"]`, val.Jsonify(s2))
+	require.Equal(t, `[null,2,"a","b"]`, val.Jsonify(s))
+
+	require.Equal(t, 0, val.EmptySet().Len())
+	require.Equal(t, "[]", val.Jsonify(val.EmptySet()))
+
+}
+
+func TestSetOperations(t *testing.T) {
+
+	a := val.SetOf(val.Long(1), val.Long(2), val.Long(3))
+	b := val.SetOf(val.Long(3), val.Long(4))
+
+	require.Equal(t, "[1,2,3,4]", val.Jsonify(a.Union(b)))
+	require.Equal(t, "[3]", val.Jsonify(a.Intersection(b)))
+	require.Equal(t, "[1,2]", val.Jsonify(a.Difference(b)))
+	require.Equal(t, "[4]", val.Jsonify(b.Difference(a)))
+	require.Equal(t, "[]", val.Jsonify(a.Intersection(val.EmptySet())))
+
+}
+
+func TestSetEncoding(t *testing.T) {
+
+	a := val.SetOf(val.Utf8("x"), val.Long(1), val.Tuple(val.True))
+	b := val.EmptySet().Add(val.Tuple(val.True)).Add(val.Double(1)).Add(val.Utf8("x"))
+
+	require.True(t, a.Equal(b))
+	require.Equal(t, val.HashCode(a), val.HashCode(b))
+
+	_, ha, err := val.Hash(a, crypto.SHA256)
+	require.NoError(t, err)
+	_, hb, err := val.Hash(b, crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, ha, hb)
+
+	mp, err := val.Pack(a)
+	require.NoError(t, err)
+	list, err := val.Unpack(mp, false)
+	require.NoError(t, err)
+	require.Equal(t, val.LIST, list.Kind())
+	require.True(t, val.SetOf(list.(val.List).Values()...).Equal(a))
+
+}
+
+func TestSetNestedNumbers(t *testing.T) {
+
+	a := val.SetOf(val.Tuple(val.Long(1)), val.EmptyImmutableMap().Put("n", val.Double(2.5)))
+	b := val.SetOf(val.Tuple(val.Double(1)), val.EmptyImmutableMap().Put("n", val.ParseNumber("2.5")))
+	require.True(t, a.Equal(b))
+
+	require.Equal(t, "92910181a16ecb4004000000000000", val.Hex(a))
+	require.Equal(t, val.Hex(a), val.Hex(b))
+
+	_, ha, err := val.Hash(a, crypto.SHA256)
+	require.NoError(t, err)
+	_, hb, err := val.Hash(b, crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, ha, hb)
+
+	ma, err := a.MarshalBinary()
+	require.NoError(t, err)
+	mb, err := b.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, ma, mb)
+
+	// set inside of the list keeps the canonical form, the rest of the list does not
+	list := val.Tuple(val.SetOf(val.Tuple(val.Double(1))), val.Tuple(val.Double(1)))
+	require.Equal(t, "92" + "919101" + "91cb3ff0000000000000", val.Hex(list))
+
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/pkg/errors"
+)
+
+/**
+	Signed envelope
+
+	{"alg": "ed25519", "kid": key id, "payload": packed value, "sig": signature}
+
+	Signature covers the packed list [alg, kid, payload], so the algorithm and the key id can not be replaced.
+	Payload must be in the canonical encoding, the one that Pack produces for the unpacked value,
+	so there is exactly one valid signed form of every value.
+*/
+
+const (
+	SignAlgEd25519 = "ed25519"
+
+	signAlgField     = "alg"
+	signKidField     = "kid"
+	signPayloadField = "payload"
+	signSigField     = "sig"
+)
+
+var ErrSignature = errors.New("invalid signature")
+
+/**
+	Returns public key for the algorithm and key id from the envelope
+*/
+
+type PublicKeyResolver func(alg, kid string) (ed25519.PublicKey, error)
+
+/**
+	Key id is the hex of the first 8 bytes of SHA-256 of the public key
+*/
+
+func KeyId(publicKey ed25519.PublicKey) string {
+	digest := sha256.Sum256(publicKey)
+	return hex.EncodeToString(digest[:8])
+}
+
+/**
+	Resolver that accepts only the single key
+*/
+
+func StaticPublicKey(publicKey ed25519.PublicKey) PublicKeyResolver {
+	kid := KeyId(publicKey)
+	return func(alg, id string) (ed25519.PublicKey, error) {
+		if id != kid {
+			return nil, errors.Errorf("unknown key id '%s'", id)
+		}
+		return publicKey, nil
+	}
+}
+
+/**
+	Signs value with the private key generated by ed25519.GenerateKey and returns the envelope
+*/
+
+func Sign(val Value, privateKey ed25519.PrivateKey) (Map, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, errors.Errorf("invalid ed25519 private key size %d", len(privateKey))
+	}
+	payload, err := Pack(val)
+	if err != nil {
+		return nil, err
+	}
+	kid := KeyId(privateKey.Public().(ed25519.PublicKey))
+	msg, err := signedMessage(SignAlgEd25519, kid, payload)
+	if err != nil {
+		return nil, err
+	}
+	sig := ed25519.Sign(privateKey, msg)
+	return ImmutableMapOf(map[string]Value {
+		signAlgField: Utf8(SignAlgEd25519),
+		signKidField: Utf8(kid),
+		signPayloadField: Raw(payload, false),
+		signSigField: Raw(sig, false),
+	}), nil
+}
+
+func signedMessage(alg, kid string, payload []byte) ([]byte, error) {
+	return Pack(Tuple(Utf8(alg), Utf8(kid), Raw(payload, false)))
+}
+
+/**
+	Verifies the envelope and returns the signed value
+
+	Returns ErrSignature if signature does not match or payload is not in the canonical encoding
+*/
+
+func Verify(envelope Value, resolver PublicKeyResolver) (Value, error) {
+
+	if envelope == nil || envelope.Kind() != MAP {
+		return nil, errors.New("envelope is not a map")
+	}
+	m := envelope.(Map)
+
+	alg, err := envelopeField(m, signAlgField)
+	if err != nil {
+		return nil, err
+	}
+	if alg.Utf8() != SignAlgEd25519 {
+		return nil, errors.Errorf("unsupported signature algorithm '%s'", alg.Utf8())
+	}
+	kid, err := envelopeField(m, signKidField)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := envelopeField(m, signPayloadField)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := envelopeField(m, signSigField)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := resolver(alg.Utf8(), kid.Utf8())
+	if err != nil {
+		return nil, err
+	}
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, errors.Errorf("invalid ed25519 public key size %d", len(publicKey))
+	}
+
+	msg, err := signedMessage(alg.Utf8(), kid.Utf8(), payload.Raw())
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(publicKey, msg, sig.Raw()) {
+		return nil, ErrSignature
+	}
+
+	val, err := Unpack(payload.Raw(), true)
+	if err != nil {
+		return nil, errors.Wrap(err, "signed payload")
+	}
+	canonical, err := Pack(val)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(canonical, payload.Raw()) {
+		return nil, errors.Wrap(ErrSignature, "payload is not in the canonical encoding")
+	}
+	return val, nil
+}
+
+func envelopeField(m Map, key string) (String, error) {
+	val := m.Get(key)
+	if val.Kind() != STRING {
+		return nil, errors.Errorf("envelope field '%s' is missing", key)
+	}
+	return val.(String), nil
+}
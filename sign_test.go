@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	config := val.EmptyImmutableMap().Put("host", val.Utf8("localhost")).Put("port", val.Long(8080))
+
+	envelope, err := val.Sign(config, priv)
+	require.NoError(t, err)
+	require.Equal(t, val.SignAlgEd25519, envelope.Get("alg").String())
+	require.Equal(t, val.KeyId(pub), envelope.Get("kid").String())
+
+	// envelope is a plain value
+	mp, err := val.Pack(envelope)
+	require.NoError(t, err)
+	received, err := val.Unpack(mp, false)
+	require.NoError(t, err)
+
+	v, err := val.Verify(received, val.StaticPublicKey(pub))
+	require.NoError(t, err)
+	require.True(t, config.Equal(v))
+
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = val.Verify(received, val.StaticPublicKey(otherPub))
+	require.Error(t, err)
+
+	// same key id, wrong key
+	_, err = val.Verify(received, func(alg, kid string) (ed25519.PublicKey, error) {
+		return otherPub, nil
+	})
+	require.True(t, errors.Is(err, val.ErrSignature))
+
+	tampered := envelope.Put("kid", val.Utf8("0000000000000000"))
+	_, err = val.Verify(tampered, func(alg, kid string) (ed25519.PublicKey, error) {
+		return pub, nil
+	})
+	require.True(t, errors.Is(err, val.ErrSignature))
+
+	_, err = val.Verify(val.Utf8("not an envelope"), val.StaticPublicKey(pub))
+	require.Error(t, err)
+
+	_, err = val.Verify(envelope.Remove("sig"), val.StaticPublicKey(pub))
+	require.Error(t, err)
+
+}
+
+func TestVerifyNonCanonical(t *testing.T) {
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	// 1 packed as int16 is a valid encoding but not the canonical one
+	payload := []byte{ 0xd1, 0x00, 0x01 }
+	kid := val.KeyId(pub)
+	msg, err := val.Pack(val.Tuple(val.Utf8(val.SignAlgEd25519), val.Utf8(kid), val.Raw(payload, false)))
+	require.NoError(t, err)
+
+	envelope := val.EmptyImmutableMap().
+		Put("alg", val.Utf8(val.SignAlgEd25519)).
+		Put("kid", val.Utf8(kid)).
+		Put("payload", val.Raw(payload, false)).
+		Put("sig", val.Raw(ed25519.Sign(priv, msg), false))
+
+	_, err = val.Verify(envelope, val.StaticPublicKey(pub))
+	require.True(t, errors.Is(err, val.ErrSignature))
+
+}
@@ -165,6 +165,9 @@ func (t sortedMapValue) Equal(val Value) bool {
 	if val == nil || val.Kind() != MAP {
 		return false
 	}
+	if vm, ok := val.(ValueMap); ok {
+		return vm.Equal(t)
+	}
 	o := val.(Map)
 	if t.Len() != o.Len() {
 		return false
@@ -0,0 +1,401 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+/**
+	Content-addressed storage of values
+
+	Values are stored by the digest of their packed bytes returned by Hash, equal values are stored once.
+	Link is the LinkExt extension with the digest of the stored value, containers with links to the shared
+	subtrees form the deduplicated DAG
+*/
+
+var ErrNotFound = errors.New("value not found")
+
+type Store interface {
+
+	/**
+	Stores value, returns the digest of it
+	*/
+
+	Put(val Value) ([]byte, error)
+
+	/**
+	Loads value by digest, returns ErrNotFound if missing
+	*/
+
+	Get(digest []byte) (Value, error)
+
+	/**
+	Checks if the value with digest is stored
+	*/
+
+	Has(digest []byte) (bool, error)
+}
+
+/**
+	Creates link to the value with the digest
+*/
+
+func Link(digest []byte) Extension {
+	tagAndData := make([]byte, len(digest)+1)
+	tagAndData[0] = byte(LinkExt)
+	copy(tagAndData[1:], digest)
+	return Unknown(tagAndData)
+}
+
+/**
+	Returns digest of the link and true if the value is a link
+*/
+
+func LinkDigest(val Value) ([]byte, bool) {
+	x, ok := val.(unknownValue)
+	if !ok || len(x) < 2 || x.Tag() != LinkExt {
+		return nil, false
+	}
+	return x.Data(), true
+}
+
+/**
+	Stores value and returns link to it
+*/
+
+func PutLink(store Store, val Value) (Value, error) {
+	digest, err := store.Put(val)
+	if err != nil {
+		return nil, err
+	}
+	return Link(digest), nil
+}
+
+/**
+	Stores every container of the tree as a separate value with children containers replaced by links,
+	returns digest of the root, shared subtrees are stored once
+*/
+
+func PutTree(store Store, val Value) ([]byte, error) {
+	root, err := putTree(store, val)
+	if err != nil {
+		return nil, err
+	}
+	return store.Put(root)
+}
+
+func putTree(store Store, val Value) (Value, error) {
+	return mapChildren(val, func(el Value) (Value, error) {
+		switch kindOf(el) {
+		case LIST, MAP:
+			el, err := putTree(store, el)
+			if err != nil {
+				return nil, err
+			}
+			return PutLink(store, el)
+		default:
+			return el, nil
+		}
+	})
+}
+
+/**
+	Loads the value if it is a link, follows links to links, other values are returned as is
+*/
+
+func Resolve(store Store, val Value) (Value, error) {
+	for {
+		digest, ok := LinkDigest(val)
+		if !ok {
+			return val, nil
+		}
+		next, err := store.Get(digest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolve link %x", digest)
+		}
+		val = next
+	}
+}
+
+/**
+	Walks the path of map keys and list indexes, loads only the links on the way
+*/
+
+func ResolvePath(store Store, val Value, path []string) (Value, error) {
+
+	val, err := Resolve(store, val)
+	if err != nil {
+		return nil, err
+	}
+
+	for depth, seg := range path {
+
+		kind := kindOf(val)
+		if kind != LIST && kind != MAP {
+			return nil, errors.Errorf("value at path %v is %v, not a container", path[:depth], kind)
+		}
+
+		var next Value
+		for _, e := range val.(Collection).Entries() {
+			if e.Key() == seg {
+				next = e.Value()
+				break
+			}
+		}
+		if next == nil {
+			return nil, errors.Errorf("key '%s' not found at path %v", seg, path[:depth])
+		}
+
+		if val, err = Resolve(store, next); err != nil {
+			return nil, errors.WithMessagef(err, "path %v", path[:depth+1])
+		}
+	}
+
+	return val, nil
+}
+
+/**
+	Replaces all links in the tree by the loaded values
+*/
+
+func ResolveDeep(store Store, val Value) (Value, error) {
+	val, err := Resolve(store, val)
+	if err != nil {
+		return nil, err
+	}
+	return mapChildren(val, func(el Value) (Value, error) {
+		return ResolveDeep(store, el)
+	})
+}
+
+/**
+	Returns container with the children replaced by fn, other values are returned as is
+*/
+
+func mapChildren(val Value, fn func(Value) (Value, error)) (Value, error) {
+
+	switch kindOf(val) {
+
+	case MAP:
+		if vm, ok := val.(immutableValueMap); ok {
+			list := make([]valueMapEntry, len(vm.list))
+			for i, e := range vm.list {
+				v, err := fn(e.value)
+				if err != nil {
+					return nil, errors.WithMessagef(err, "key '%s'", e.key.String())
+				}
+				list[i] = valueMapEntry{e.key, v}
+			}
+			return newImmutableValueMap(list), nil
+		}
+		entries := val.(Map).Entries()
+		list := make([]MapEntry, len(entries))
+		for i, e := range entries {
+			v, err := fn(e.Value())
+			if err != nil {
+				return nil, errors.WithMessagef(err, "key '%s'", e.Key())
+			}
+			list[i] = ImmutableEntry(e.Key(), v)
+		}
+		return ImmutableMap(list, true), nil
+
+	case LIST:
+		if sparse, ok := val.(sparseListValue); ok {
+			items := make([]ListItem, len(sparse))
+			for i, item := range sparse {
+				v, err := fn(item.Value())
+				if err != nil {
+					return nil, errors.WithMessagef(err, "index %d", item.Key())
+				}
+				items[i] = ImmutableItem(item.Key(), v)
+			}
+			return SparseList(items, true), nil
+		}
+		values := val.(List).Values()
+		list := make([]Value, len(values))
+		for i, el := range values {
+			v, err := fn(el)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "index %d", i)
+			}
+			list[i] = v
+		}
+		return ImmutableList(list), nil
+
+	default:
+		return val, nil
+	}
+}
+
+/**
+	Store in memory, safe for concurrent use
+*/
+
+type MemoryStore struct {
+	hash     crypto.Hash
+	mu       sync.RWMutex
+	objects  map[string][]byte
+}
+
+func NewMemoryStore(hash crypto.Hash) (*MemoryStore, error) {
+	if !hash.Available() {
+		return nil, errors.Errorf("hash function %v is not linked", hash)
+	}
+	return &MemoryStore{hash: hash, objects: make(map[string][]byte)}, nil
+}
+
+func (s *MemoryStore) Put(val Value) ([]byte, error) {
+	data, digest, err := Hash(val, s.hash)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if _, ok := s.objects[string(digest)]; !ok {
+		s.objects[string(digest)] = data
+	}
+	s.mu.Unlock()
+	return digest, nil
+}
+
+func (s *MemoryStore) Get(digest []byte) (Value, error) {
+	s.mu.RLock()
+	data, ok := s.objects[string(digest)]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, errors.Wrapf(ErrNotFound, "digest %x", digest)
+	}
+	return Unpack(data, false)
+}
+
+func (s *MemoryStore) Has(digest []byte) (bool, error) {
+	s.mu.RLock()
+	_, ok := s.objects[string(digest)]
+	s.mu.RUnlock()
+	return ok, nil
+}
+
+/**
+	Number of stored values
+*/
+
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.objects)
+}
+
+/**
+	Store in the directory, every value is the file named by hex digest in the subdirectory of the first byte
+
+	Files are written atomically and verified against the digest on read
+*/
+
+type DirStore struct {
+	dir   string
+	hash  crypto.Hash
+}
+
+func NewDirStore(dir string, hash crypto.Hash) (*DirStore, error) {
+	if !hash.Available() {
+		return nil, errors.Errorf("hash function %v is not linked", hash)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DirStore{dir: dir, hash: hash}, nil
+}
+
+func (s *DirStore) path(digest []byte) (string, error) {
+	if len(digest) != s.hash.Size() {
+		return "", errors.Errorf("invalid digest length %d, expected %d", len(digest), s.hash.Size())
+	}
+	name := hex.EncodeToString(digest)
+	return filepath.Join(s.dir, name[:2], name), nil
+}
+
+func (s *DirStore) Put(val Value) ([]byte, error) {
+
+	data, digest, err := Hash(val, s.hash)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := s.path(digest)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return digest, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return nil, err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, errors.Wrapf(err, "write %s", path)
+	}
+
+	return digest, nil
+}
+
+func (s *DirStore) Get(digest []byte) (Value, error) {
+
+	path, err := s.path(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Wrapf(ErrNotFound, "digest %x", digest)
+		}
+		return nil, err
+	}
+
+	h := s.hash.New()
+	h.Write(data)
+	if !bytes.Equal(h.Sum(nil), digest) {
+		return nil, errors.Errorf("corrupt value file %s", path)
+	}
+
+	return Unpack(data, false)
+}
+
+func (s *DirStore) Has(digest []byte) (bool, error) {
+	path, err := s.path(digest)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+	"github.com/pkg/errors"
+	val "github.com/codeallergy/value"
+	"github.com/stretchr/testify/require"
+)
+
+func sharedDocument() val.Map {
+	shared := val.EmptyImmutableMap().Put("name", val.Utf8("shared")).Put("items", val.Tuple(val.Long(1), val.Long(2)))
+	return val.EmptyImmutableMap().
+		Put("a", shared).
+		Put("b", val.Tuple(shared, val.Utf8("x"))).
+		Put("c", val.Long(3))
+}
+
+func testStore(t *testing.T, store val.Store) {
+
+	doc := sharedDocument()
+
+	digest, err := store.Put(doc)
+	require.NoError(t, err)
+	_, expected, err := val.Hash(doc, crypto.SHA256)
+	require.NoError(t, err)
+	require.Equal(t, expected, digest)
+
+	ok, err := store.Has(digest)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	loaded, err := store.Get(digest)
+	require.NoError(t, err)
+	require.True(t, doc.Equal(loaded))
+
+	again, err := store.Put(doc)
+	require.NoError(t, err)
+	require.Equal(t, digest, again)
+
+	missing := make([]byte, len(digest))
+	ok, err = store.Has(missing)
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, err = store.Get(missing)
+	require.True(t, errors.Is(err, val.ErrNotFound))
+
+	// tree with links
+	root, err := val.PutTree(store, doc)
+	require.NoError(t, err)
+
+	stored, err := store.Get(root)
+	require.NoError(t, err)
+	link := stored.(val.Map).Get("a")
+	require.Equal(t, val.UNKNOWN, link.Kind())
+	_, isLink := val.LinkDigest(link)
+	require.True(t, isLink)
+	require.Equal(t, val.Long(3), stored.(val.Map).Get("c"))
+
+	name, err := val.ResolvePath(store, val.Link(root), []string{"b", "0", "name"})
+	require.NoError(t, err)
+	require.Equal(t, "shared", name.String())
+
+	_, err = val.ResolvePath(store, val.Link(root), []string{"b", "5"})
+	require.Error(t, err)
+
+	full, err := val.ResolveDeep(store, val.Link(root))
+	require.NoError(t, err)
+	require.True(t, doc.Equal(full))
+
+	resolved, err := val.Resolve(store, val.Long(1))
+	require.NoError(t, err)
+	require.Equal(t, val.Long(1), resolved)
+
+	_, err = val.ResolveDeep(store, val.Tuple(val.Link(missing)))
+	require.True(t, errors.Is(err, val.ErrNotFound))
+}
+
+func TestMemoryStore(t *testing.T) {
+
+	store, err := val.NewMemoryStore(crypto.SHA256)
+	require.NoError(t, err)
+	testStore(t, store)
+
+	// shared subtree is stored once
+	before := store.Len()
+	_, err = val.PutTree(store, val.EmptyImmutableMap().Put("copy", sharedDocument().Get("a")))
+	require.NoError(t, err)
+	require.Equal(t, before+1, store.Len())
+
+}
+
+func TestStoreHashNotLinked(t *testing.T) {
+
+	_, err := val.NewMemoryStore(crypto.Hash(0))
+	require.Error(t, err)
+	_, err = val.NewMemoryStore(crypto.MD4)
+	require.Error(t, err)
+	_, err = val.NewDirStore(t.TempDir(), crypto.MD4)
+	require.Error(t, err)
+
+}
+
+func TestDirStore(t *testing.T) {
+
+	dir := t.TempDir()
+	store, err := val.NewDirStore(dir, crypto.SHA256)
+	require.NoError(t, err)
+	testStore(t, store)
+
+	digest, err := store.Put(val.Utf8("payload"))
+	require.NoError(t, err)
+
+	_, err = store.Get(digest[:4])
+	require.Error(t, err)
+
+	// corrupted file is detected
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	for _, file := range files {
+		require.NoError(t, os.WriteFile(file, []byte{0xc0}, 0644))
+	}
+	_, err = store.Get(digest)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, val.ErrNotFound))
+
+}
+
+func TestLink(t *testing.T) {
+
+	link := val.Link([]byte{1, 2, 3})
+	require.Equal(t, "c70304010203", val.Hex(link))
+
+	b, err := val.Pack(link)
+	require.NoError(t, err)
+	parsed, err := val.Unpack(b, true)
+	require.NoError(t, err)
+	digest, ok := val.LinkDigest(parsed)
+	require.True(t, ok)
+	require.Equal(t, []byte{1, 2, 3}, digest)
+
+	_, ok = val.LinkDigest(val.Utf8("x"))
+	require.False(t, ok)
+
+	require.Contains(t, val.Dump(b), "link 010203")
+
+}
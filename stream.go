@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"context"
+	"io"
+)
+
+/**
+	Packs values from the channel until it is closed or the context is done
+
+	Returns the context error on cancellation or the first write error
+*/
+
+func WriteStreamContext(ctx context.Context, w io.Writer, valueC <-chan Value) error {
+
+	p := MessagePacker(w)
+
+	for p.Error() == nil {
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case val, ok := <-valueC:
+			if !ok {
+				return p.Error()
+			}
+			if val != nil {
+				val.Pack(p)
+			} else {
+				p.PackNil()
+			}
+		}
+
+	}
+
+	return p.Error()
+}
+
+/**
+	Reads values to the channel until the end of stream, always closes the channel
+
+	Returns nil on the clean end of stream, io.ErrUnexpectedEOF if the last value is truncated,
+	the context error if the consumer went away; blocking read of the source is not interrupted by the context
+*/
+
+func ReadStreamContext(ctx context.Context, r io.Reader, out chan<- Value) error {
+
+	defer close(out)
+
+	s := NewStreamReader(r)
+
+	for {
+
+		value, err := s.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- value:
+		}
+	}
+
+}
+
+/**
+	Iterator over the stream of values, alternative to ReadStream without goroutines and channels
+
+	Not safe for concurrent use.
+*/
+
+type StreamReader struct {
+	unpacker  *messageIOUnpacker
+	parser    Parser
+	err       error
+}
+
+func NewStreamReader(r io.Reader) *StreamReader {
+	return &StreamReader{
+		unpacker: MessageReader(r),
+		parser: MessageParser(),
+	}
+}
+
+/**
+	Reads next value
+
+	Returns io.EOF on the clean end of stream, after any error all next calls return the same error
+*/
+
+func (s *StreamReader) Next() (Value, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	value, err := doParse(s.unpacker, s.parser)
+	if err != nil {
+		s.err = err
+		return nil, err
+	}
+	return value, nil
+}
+
+/**
+	Number of bytes consumed by the returned values
+*/
+
+func (s *StreamReader) Offset() int64 {
+	return s.unpacker.Offset()
+}
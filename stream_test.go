@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	val "github.com/codeallergy/value"
+	"github.com/stretchr/testify/require"
+)
+
+func testStreamBytes(t *testing.T) []byte {
+	buf := bytes.Buffer{}
+	for i := 0; i != numIterations; i++ {
+		require.Nil(t, val.Write(&buf, val.Long(int64(i * 1000))))
+	}
+	return buf.Bytes()
+}
+
+func TestStreamReader(t *testing.T) {
+
+	stream := testStreamBytes(t)
+	s := val.NewStreamReader(iotest.OneByteReader(bytes.NewReader(stream)))
+
+	for i := 0; i != numIterations; i++ {
+		v, err := s.Next()
+		require.Nil(t, err)
+		require.True(t, val.Long(int64(i * 1000)).Equal(v))
+	}
+
+	_, err := s.Next()
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, int64(len(stream)), s.Offset())
+
+}
+
+func TestStreamReaderTruncated(t *testing.T) {
+
+	stream := testStreamBytes(t)
+	s := val.NewStreamReader(bytes.NewReader(stream[:len(stream)-1]))
+
+	var err error
+	for err == nil {
+		_, err = s.Next()
+	}
+	require.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+	_, again := s.Next()
+	require.Equal(t, err, again)
+
+	out := make(chan val.Value, numIterations)
+	err = val.ReadStreamContext(context.Background(), bytes.NewReader(stream[:len(stream)-1]), out)
+	require.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+}
+
+func TestReadStreamCancel(t *testing.T) {
+
+	stream := testStreamBytes(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	out := make(chan val.Value)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- val.ReadStreamContext(ctx, bytes.NewReader(stream), out)
+	}()
+
+	<-out
+	cancel()
+
+	require.Equal(t, context.Canceled, <-errC)
+
+	for range out {
+	}
+
+}
+
+type failingWriter struct {
+}
+
+var errTestWrite = errors.New("write failed")
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, errTestWrite
+}
+
+func TestWriteStreamContext(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	valueC := make(chan val.Value)
+	err := val.WriteStreamContext(ctx, &bytes.Buffer{}, valueC)
+	require.Equal(t, context.Canceled, err)
+
+	valueC = make(chan val.Value, 1)
+	valueC <- val.Utf8("value")
+	err = val.WriteStreamContext(context.Background(), failingWriter{}, valueC)
+	require.Equal(t, errTestWrite, err)
+
+}
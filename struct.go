@@ -16,12 +16,20 @@ import (
 
 
 func PackStruct(obj interface{}) ([]byte, error) {
+	return PackStructWith(obj, nil)
+}
+
+/**
+	Packs struct and seals the fields tagged by `encrypt` with the keys from the keyring
+*/
+
+func PackStructWith(obj interface{}, keyring Keyring) ([]byte, error) {
 	buf := bytes.Buffer{}
 	p := MessagePacker(&buf)
 	if obj != nil {
 		if val, ok := obj.(Value); ok {
 			val.Pack(p)
-		} else if err := reflectPackStruct(p, obj); err != nil {
+		} else if err := reflectPackStruct(p, obj, keyring); err != nil {
 			return nil, err
 		}
 	} else {
@@ -31,6 +39,14 @@ func PackStruct(obj interface{}) ([]byte, error) {
 }
 
 func UnpackStruct(buf []byte, obj interface{}, copy bool) error {
+	return UnpackStructWith(buf, obj, copy, nil)
+}
+
+/**
+	Unpacks struct and opens the fields tagged by `encrypt` with the keys from the keyring
+*/
+
+func UnpackStructWith(buf []byte, obj interface{}, copy bool, keyring Keyring) error {
 	unpacker := MessageUnpacker(buf, copy)
 	parser := MessageParser()
 	classPtr := reflect.TypeOf(obj)
@@ -42,11 +58,11 @@ func UnpackStruct(buf []byte, obj interface{}, copy bool) error {
 	} else {
 		valuePtr := reflect.ValueOf(obj)
 		value := valuePtr.Elem()
-		return ParseStruct(unpacker, parser, value, schema)
+		return parseStruct(unpacker, parser, value, schema, keyring)
 	}
 }
 
-func reflectPackStruct(p *messagePacker, obj interface{}) error {
+func reflectPackStruct(p *messagePacker, obj interface{}, keyring Keyring) error {
 	classPtr := reflect.TypeOf(obj)
 	if classPtr.Kind() != reflect.Ptr {
 		return errors.Errorf("non-pointer instance is not allowed in '%v'", classPtr)
@@ -57,7 +73,7 @@ func reflectPackStruct(p *messagePacker, obj interface{}) error {
 	}
 	valuePtr := reflect.ValueOf(obj)
 	value := valuePtr.Elem()
-	return doReflectPackStruct(p, value, schema)
+	return doReflectPackStruct(p, value, schema, keyring)
 }
 
 type packingField struct {
@@ -65,7 +81,7 @@ type packingField struct {
 	fieldValue  reflect.Value
 }
 
-func doReflectPackStruct(p *messagePacker, value reflect.Value, schema *Schema) error {
+func doReflectPackStruct(p *messagePacker, value reflect.Value, schema *Schema, keyring Keyring) error {
 	var list []*packingField
 	cnt := 0
 	for _, field := range schema.SortedFields {
@@ -98,13 +114,13 @@ func doReflectPackStruct(p *messagePacker, value reflect.Value, schema *Schema)
 					p.PackLong(int64(entry.field.Tag))
 				}
 				elem := entry.fieldValue.Index(i)
-				if err := doReflectPackValue(p, elem, entry); err != nil {
+				if err := doReflectPackValue(p, elem, entry, keyring); err != nil {
 					return err
 				}
 			}
 		} else {
 			p.PackLong(int64(entry.field.Tag))
-			if err := doReflectPackValue(p,  entry.fieldValue, entry); err != nil {
+			if err := doReflectPackValue(p, entry.fieldValue, entry, keyring); err != nil {
 				return err
 			}
 		}
@@ -112,14 +128,17 @@ func doReflectPackStruct(p *messagePacker, value reflect.Value, schema *Schema)
 	return nil
 }
 
-func doReflectPackValue(p *messagePacker, value reflect.Value, entry *packingField) error {
+func doReflectPackValue(p *messagePacker, value reflect.Value, entry *packingField, keyring Keyring) error {
 	if entry.field.Struct {
-		if err := doReflectPackStruct(p, value.Elem(), entry.field.FieldSchema); err != nil {
+		if err := doReflectPackStruct(p, value.Elem(), entry.field.FieldSchema, keyring); err != nil {
 			return errors.Errorf("can not pack field %v, inner struct error %v", value, err)
 		}
 	} else {
 		fieldObject := value.Interface()
 		if val, ok := fieldObject.(Value); ok {
+			if entry.field.EncryptKey != "" {
+				return packEncryptedField(p, val, entry.field, keyring)
+			}
 			val.Pack(p)
 		} else {
 			return errors.Errorf("can not convert field %v to value.Value", value)
@@ -137,6 +156,8 @@ type Field struct {
 	Repeated       bool
 	FieldSchema    *Schema
 	Tag            int
+	EncryptKey     string
+	Sensitive      bool
 }
 
 type Schema struct {
@@ -181,6 +202,11 @@ func doReflectSchema(classPtr reflect.Type) (*Schema, error) {
 		if rep, ok := field.Tag.Lookup("repeated"); ok {
 			repeated, _ = strconv.ParseBool(rep)
 		}
+		encryptKey := field.Tag.Get("encrypt")
+		sensitive := false
+		if sen, ok := field.Tag.Lookup("sensitive"); ok {
+			sensitive, _ = strconv.ParseBool(sen)
+		}
 		tagStr, ok := field.Tag.Lookup("tag")
 		if !ok {
 			return nil, errors.Errorf("no tag in field '%s' in class '%v'", field.Name, classPtr)
@@ -204,9 +230,13 @@ func doReflectSchema(classPtr reflect.Type) (*Schema, error) {
 				Struct:     false,
 				Repeated:   repeated,
 				Tag:        tag,
+				EncryptKey: encryptKey,
+				Sensitive:  sensitive,
 			}
 			fields[tag] = f
 			sortedFields = append(sortedFields, f)
+		} else if encryptKey != "" {
+			return nil, errors.Errorf("encrypted field '%s' in class '%v' must implement value.Value interface", field.Name, classPtr)
 		} else if fieldType.Kind() != reflect.Ptr {
 			return nil, errors.Errorf("tagged field '%s' in class '%v' with type '%v' does not implement value.Value interface and non-ptr", field.Name, field.Type, classPtr)
 		} else if fieldSchema, err := reflectSchema(fieldType); err != nil {
@@ -221,6 +251,7 @@ func doReflectSchema(classPtr reflect.Type) (*Schema, error) {
 				Repeated: repeated,
 				FieldSchema: fieldSchema,
 				Tag: tag,
+				Sensitive: sensitive,
 			}
 			fields[tag] = f
 			sortedFields = append(sortedFields, f)
@@ -235,6 +266,10 @@ func doReflectSchema(classPtr reflect.Type) (*Schema, error) {
 
 
 func ParseStruct(unpacker Unpacker, parser Parser, value reflect.Value, schema *Schema) error {
+	return parseStruct(unpacker, parser, value, schema, nil)
+}
+
+func parseStruct(unpacker Unpacker, parser Parser, value reflect.Value, schema *Schema, keyring Keyring) error {
 	format, header := unpacker.Next()
 	if format != MapHeader {
 		return errors.Errorf("expected MapHeader for struct, but got %v", format)
@@ -244,7 +279,7 @@ func ParseStruct(unpacker Unpacker, parser Parser, value reflect.Value, schema *
 		return parser.Error()
 	}
 	for i := 0; i < cnt; i++ {
-		key, err := doParse(unpacker, parser)
+		key, err := doParseElement(unpacker, parser)
 		if err != nil {
 			return errors.Errorf("fail to parse key on position %d, %v", i, err)
 		}
@@ -274,14 +309,14 @@ func ParseStruct(unpacker Unpacker, parser Parser, value reflect.Value, schema *
 						if field.Struct {
 							structValue := reflect.New(elemValue.Type().Elem())
 							elemValue.Set(structValue)
-							err := ParseStruct(unpacker, parser, elemValue.Elem(), field.FieldSchema)
+							err := parseStruct(unpacker, parser, elemValue.Elem(), field.FieldSchema, keyring)
 							if err != nil {
 								return errors.Errorf("fail to set struct value %v", err)
 							}
 						} else {
-							val, err := doParse(unpacker, parser)
+							val, err := parseFieldElement(unpacker, parser, field, keyring)
 							if err != nil {
-								return errors.Errorf("fail to parse value %v", err)
+								return errors.WithMessage(err, "fail to parse value")
 							}
 							err = setFieldValue(elemValue, field.FieldType.Elem(), val)
 							if err != nil {
@@ -304,16 +339,16 @@ func ParseStruct(unpacker Unpacker, parser Parser, value reflect.Value, schema *
 						structValue := reflect.New(ptrType.Elem())
 						elemValue.Set(structValue)
 						sliceValue = reflect.Append(sliceValue, elemValue)
-						err := ParseStruct(unpacker, parser, elemValue.Elem(), field.FieldSchema)
+						err := parseStruct(unpacker, parser, elemValue.Elem(), field.FieldSchema, keyring)
 						if err != nil {
 							return errors.Errorf("fail to set struct value %v", err)
 						}
 					} else {
 						elemValue = reflect.New(field.FieldType.Elem()).Elem()
 						sliceValue = reflect.Append(sliceValue, elemValue)
-						val, err := doParse(unpacker, parser)
+						val, err := parseFieldElement(unpacker, parser, field, keyring)
 						if err != nil {
-							return errors.Errorf("fail to parse value %v", err)
+							return errors.WithMessage(err, "fail to parse value")
 						}
 						err = setFieldValue(elemValue, field.FieldType.Elem(), val)
 						if err != nil {
@@ -323,9 +358,9 @@ func ParseStruct(unpacker Unpacker, parser Parser, value reflect.Value, schema *
 					fieldValue.Set(sliceValue)
 				}
 			} else {
-				err = parseFieldValue(unpacker, parser, field, fieldValue)
+				err = parseFieldValue(unpacker, parser, field, fieldValue, keyring)
 				if err != nil {
-					return errors.Errorf("parse field on position %d, %v", i, err)
+					return errors.WithMessagef(err, "parse field on position %d", i)
 				}
 			}
 		} else {
@@ -335,7 +370,7 @@ func ParseStruct(unpacker Unpacker, parser Parser, value reflect.Value, schema *
 	return nil
 }
 
-func parseFieldValue(unpacker Unpacker, parser Parser, field *Field, fieldValue reflect.Value) error {
+func parseFieldValue(unpacker Unpacker, parser Parser, field *Field, fieldValue reflect.Value, keyring Keyring) error {
 	if field.Struct {
 		if fieldValue.IsNil() {
 			if fieldValue.CanSet() {
@@ -344,14 +379,14 @@ func parseFieldValue(unpacker Unpacker, parser Parser, field *Field, fieldValue
 				return errors.Errorf("can not set empty struct value to field %v", field.FieldName)
 			}
 		}
-		err := ParseStruct(unpacker, parser, fieldValue.Elem(), field.FieldSchema)
+		err := parseStruct(unpacker, parser, fieldValue.Elem(), field.FieldSchema, keyring)
 		if err != nil {
 			return errors.Errorf("fail to set struct value %v", err)
 		}
 	} else {
-		val, err := doParse(unpacker, parser)
+		val, err := parseFieldElement(unpacker, parser, field, keyring)
 		if err != nil {
-			return errors.Errorf("fail to parse value %v", err)
+			return errors.WithMessage(err, "fail to parse value")
 		}
 		err = setFieldValue(fieldValue, field.FieldType, val)
 		if err != nil {
@@ -363,6 +398,10 @@ func parseFieldValue(unpacker Unpacker, parser Parser, field *Field, fieldValue
 
 
 func setFieldValue(fieldValue reflect.Value, fieldType reflect.Type, val Value) error {
+	if val == nil {
+		// nothing to set, keep zero value
+		return nil
+	}
 	if fieldValue.CanSet() {
 		if !val.Class().AssignableTo(fieldType) {
 			return errors.Errorf("expected value type %v, actual %v", fieldType, val.Class())
@@ -21,9 +21,9 @@ func TestUnknown(t *testing.T) {
 	v := val.Unknown(tagAndData)
 
 	require.Equal(t, val.UNKNOWN, v.Kind())
-	require.Equal(t, val.UnknownPrefix+ val.Base64Prefix + "AwE", v.String())
+	require.Equal(t, val.UnknownPrefix+ val.Base64Prefix + "BQE", v.String())
 	require.Equal(t, "\"" + v.String() + "\"", val.Jsonify(v))
-	require.Equal(t, "d40301", val.Hex(v))
+	require.Equal(t, "d40501", val.Hex(v))
 	require.Equal(t, 0, bytes.Compare(tagAndData, v.Native()))
 
 	mp, err := val.Pack(v)
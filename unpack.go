@@ -9,19 +9,59 @@ package value
 import (
 	"github.com/pkg/errors"
 	"io"
-	"strconv"
 )
 
 
+/**
+	Optional interface implemented by unpackers that track position and keep I/O errors
+*/
+
+type unpackerState interface {
+	Offset() int64
+
+	Error() error
+}
+
+/**
+	Clean io.EOF is returned as is, because it is the only way to detect end of the stream,
+	all other errors are decorated by the offset in the input when available
+*/
+
+func endOfInput(unpacker Unpacker, err error) error {
+	state, ok := unpacker.(unpackerState)
+	if !ok {
+		return err
+	}
+	if state.Error() != nil {
+		return state.Error()
+	}
+	if err == io.EOF {
+		return err
+	}
+	return errors.Wrapf(err, "offset %d", state.Offset())
+}
+
+/**
+	Parses value inside of container, where the end of input is always unexpected
+*/
+
+func doParseElement(unpacker Unpacker, parser Parser) (Value, error) {
+	val, err := doParse(unpacker, parser)
+	if err == io.EOF {
+		err = endOfInput(unpacker, io.ErrUnexpectedEOF)
+	}
+	return val, err
+}
+
 func doParse(unpacker Unpacker, parser Parser) (Value, error) {
 
 	format, header := unpacker.Next()
 
 	switch format {
 	case EOF:
-		return nil, io.EOF
+		return nil, endOfInput(unpacker, io.EOF)
 	case UnexpectedEOF:
-		return nil, io.ErrUnexpectedEOF
+		return nil, endOfInput(unpacker, io.ErrUnexpectedEOF)
 	case NilToken:
 		return Null, nil
 	case BoolToken:
@@ -83,7 +123,7 @@ func doParseList(header []byte, unpacker Unpacker, parser Parser) (List, error)
 	}
 	list := make([]Value, cnt)
 	for i := 0; i < cnt; i++ {
-		el, err := doParse(unpacker, parser)
+		el, err := doParseElement(unpacker, parser)
 		if err != nil {
 			return nil, err
 		}
@@ -100,19 +140,17 @@ func doParseMap(header []byte, unpacker Unpacker, parser Parser) (Value, error)
 	if cnt == 0 {
 		return EmptyImmutableMap(), nil
 	}
-	var sparseListItems []ListItem
-	mayBeList := false
-	var sortedMapEntries []MapEntry
-	sorted := true
-	var prevListKey int64
-	var prevMapKey string
+
+	keys := make([]Value, 0, cnt)
+	values := make([]Value, 0, cnt)
+	allStr, allLong := true, true
 
 	for i := 0; i < cnt; i++ {
-		key, err := doParse(unpacker, parser)
+		key, err := doParseElement(unpacker, parser)
 		if err != nil {
 			return nil, err
 		}
-		value, err := doParse(unpacker, parser)
+		value, err := doParseElement(unpacker, parser)
 		if err != nil {
 			return nil, err
 		}
@@ -122,58 +160,52 @@ func doParseMap(header []byte, unpacker Unpacker, parser Parser) (Value, error)
 			continue
 		}
 
-		// first element
-		if i == 0 {
-			if key.Kind() == NUMBER {
-				// try to build sparse list
-				mayBeList = true
-				sparseListItems = make([]ListItem, cnt)
-			} else {
-				mayBeList = false
-				sortedMapEntries = make([]MapEntry, cnt)
-			}
+		if key.Kind() != STRING || key.(String).Type() != UTF8 {
+			allStr = false
 		}
+		if key.Kind() != NUMBER || key.(Number).Type() != LONG {
+			allLong = false
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+
+	sorted := true
+
+	switch {
 
-		if mayBeList {
-
-			if key.Kind() == NUMBER {
-				k := key.(Number).Long()
-				if i > 0 && prevListKey > k {
-					sorted = false
-				}
-				sparseListItems[i] = ImmutableItem(int(k), value)
-				prevListKey = k
-			} else {
-				// not a list
-				mayBeList = false
-				sortedMapEntries = make([]MapEntry, cnt)
-				for j := 0; j < i; j++ {
-					item := sparseListItems[i]
-					sortedMapEntries[i] = ImmutableEntry(strconv.Itoa(item.Key()), item.Value())
-				}
-				k := key.String()
-				if i > 0 && prevMapKey > k {
-					sorted = false
-				}
-				sortedMapEntries[i] = ImmutableEntry(k, value)
-				prevMapKey = k
+	case len(keys) == 0:
+		return EmptyImmutableMap(), nil
+
+	case allLong:
+		sparseListItems := make([]ListItem, len(keys))
+		for i, key := range keys {
+			k := key.(Number).Long()
+			if i > 0 && keys[i-1].(Number).Long() > k {
+				sorted = false
 			}
+			sparseListItems[i] = ImmutableItem(int(k), values[i])
+		}
+		return SparseList(sparseListItems, sorted), nil
 
-		} else {
+	case allStr:
+		sortedMapEntries := make([]MapEntry, len(keys))
+		for i, key := range keys {
 			k := key.String()
-			if i > 0 && prevMapKey > k {
+			if i > 0 && keys[i-1].String() > k {
 				sorted = false
 			}
-			sortedMapEntries[i] = ImmutableEntry(k, value)
-			prevMapKey = k
+			sortedMapEntries[i] = ImmutableEntry(k, values[i])
 		}
-
-	}
-
-	if mayBeList {
-		return SparseList(sparseListItems, sorted), nil
-	} else {
 		return ImmutableMap(sortedMapEntries, sorted), nil
+
+	default:
+		// binary, boolean, double or mixed keys
+		list := make([]valueMapEntry, len(keys))
+		for i, key := range keys {
+			list[i] = valueMapEntry{key: key, value: nullIfNil(values[i])}
+		}
+		return newImmutableValueMap(list), nil
 	}
 
 }
@@ -7,6 +7,7 @@ package value
 
 import (
 	"bytes"
+	"context"
 	"crypto"
 	"crypto/rand"
 	"encoding/hex"
@@ -16,9 +17,9 @@ import (
 	"strings"
 )
 
-func Pack(val Value) ([]byte, error) {
+func Pack(val Value, options ...PackOption) ([]byte, error) {
 	buf := bytes.Buffer{}
-	p := MessagePacker(&buf)
+	p := MessagePacker(&buf, options...)
 	if val != nil {
 		val.Pack(p)
 	} else {
@@ -39,8 +40,19 @@ func Read(r io.Reader) (Value, error) {
 	return Parse(unpacker, parser)
 }
 
-func Write(w io.Writer, val Value) error {
-	p := MessagePacker(w)
+/**
+	Reads value at the offset from random access source, returns the offset right after the value
+*/
+
+func ReadAt(r io.ReaderAt, off int64) (Value, int64, error) {
+	unpacker := MessageReaderAt(r, off)
+	parser := MessageParser()
+	val, err := Parse(unpacker, parser)
+	return val, unpacker.Offset(), err
+}
+
+func Write(w io.Writer, val Value, options ...PackOption) error {
+	p := MessagePacker(w, options...)
 	val.Pack(p)
 	return p.Error()
 }
@@ -60,9 +72,14 @@ func Jsonify(val Value) string {
 	return out.String()
 }
 
-// return data, hash, error
-func Hash(val Value, hash crypto.Hash) ([]byte, []byte, error) {
-	data, err := Pack(val)
+// return data, hash, error, use WithCanonicalNumbers to get the same hash for equal numbers of different types
+// digests of immutable containers are cached, see MemoizeContainers
+func Hash(val Value, hash crypto.Hash, options ...PackOption) ([]byte, []byte, error) {
+	if d, ok := val.(digester); ok && MemoizeContainers {
+		data, digest := d.digest(hash, canonicalOption(options))
+		return append([]byte(nil), data...), append([]byte(nil), digest...), nil
+	}
+	data, err := Pack(val, options...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -90,6 +107,9 @@ var ErrUnseal = errors.New("unseal error")
 
 func Unseal(encrypted []byte, senderPublicKey, recipientPrivateKey *[32]byte) (Value, error) {
 	var decryptNonce [24]byte
+	if len(encrypted) < len(decryptNonce) {
+		return nil, ErrUnseal
+	}
 	copy(decryptNonce[:], encrypted[:24])
 	decrypted, ok := box.Open(nil, encrypted[24:], &decryptNonce, senderPublicKey, recipientPrivateKey)
 	if !ok {
@@ -110,41 +130,11 @@ func Parse(unpacker Unpacker, parser Parser) (Value, error) {
 }
 
 func WriteStream(w io.Writer, valueC <-chan Value) error {
-
-	p := MessagePacker(w)
-
-	for p.Error() == nil {
-		val, ok := <- valueC
-
-		if !ok {
-			break
-		}
-
-		val.Pack(p)
-
-	}
-
-	return p.Error()
+	return WriteStreamContext(context.Background(), w, valueC)
 }
 
 func ReadStream(r io.Reader, out chan<- Value) error {
-
-	defer close(out)
-
-	unpacker := MessageReader(r)
-	parser := MessageParser()
-
-	for {
-
-		value, err := doParse(unpacker, parser)
-		if err != nil {
-			return err
-		}
-
-		out <- value
-	}
-
-	return nil
+	return ReadStreamContext(context.Background(), r, out)
 }
 
 func CopyOf(src []Value) []Value {
@@ -0,0 +1,483 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value
+
+import (
+	"bytes"
+	"crypto"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+/**
+	Map with keys of any kind
+
+	Entries keep the order and encoding of the keys, so maps with binary, boolean or mixed keys written
+	by other MessagePack libraries are packed back byte-for-byte, new keys are appended to the end.
+	Lookup, Equal and Compare use the index of keys sorted by Compare, so the order of entries does not matter for them.
+	Unpacker produces it when map keys are neither all utf8 strings nor all integers.
+
+	Keys are unique, for equal keys like Long(1) and Double(1) the first key is kept with the last value.
+	Methods of Map with string keys look up Utf8 keys, Entries and Keys convert keys by String(),
+	Insert and InsertAll replace the value as keys are unique.
+*/
+
+type ValueMap interface {
+	Map
+
+	/**
+	List keys as values
+	*/
+
+	KeyValues() []Value
+
+	/**
+		Gets value by the key
+
+	    return Value or Null
+	*/
+
+	GetKey(Value) Value
+
+	/**
+	Checks if map has the key
+	*/
+
+	HasKey(Value) bool
+
+	/**
+	Puts value by the key, replaces if it exist
+	*/
+
+	PutKey(key Value, value Value) ValueMap
+
+	/**
+	Removes value by the key
+	*/
+
+	RemoveKey(Value) ValueMap
+}
+
+type valueMapEntry struct {
+	key    Value
+	value  Value
+}
+
+type immutableValueMap struct {
+	list   []valueMapEntry
+	index  []int   // positions in list sorted by Compare of keys
+	memo   *memoRef
+}
+
+var immutableValueMapClass = reflect.TypeOf((*immutableValueMap)(nil)).Elem()
+
+/**
+	Creates map with entries in the given order, removes duplicate keys
+*/
+
+func newImmutableValueMap(list []valueMapEntry) immutableValueMap {
+	index := sortedValueMapIndex(list)
+	for i := 1; i < len(index); i++ {
+		if Compare(list[index[i-1]].key, list[index[i]].key) == 0 {
+			list = dedupValueMapEntries(list, index)
+			index = sortedValueMapIndex(list)
+			break
+		}
+	}
+	return immutableValueMap{list: list, index: index, memo: newMemoRef()}
+}
+
+func sortedValueMapIndex(list []valueMapEntry) []int {
+	index := make([]int, len(list))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool {
+		return Compare(list[index[i]].key, list[index[j]].key) < 0
+	})
+	return index
+}
+
+/**
+	Equal keys are adjacent in the stable sorted index in the order of entries,
+	the first entry of every group takes the value of the last one
+*/
+
+func dedupValueMapEntries(list []valueMapEntry, index []int) []valueMapEntry {
+	dst := make([]valueMapEntry, len(list))
+	copy(dst, list)
+	drop := make([]bool, len(list))
+	for i := 0; i < len(index); {
+		j := i + 1
+		for j < len(index) && Compare(list[index[i]].key, list[index[j]].key) == 0 {
+			drop[index[j]] = true
+			j++
+		}
+		dst[index[i]].value = list[index[j-1]].value
+		i = j
+	}
+	n := 0
+	for i, e := range dst {
+		if !drop[i] {
+			dst[n] = e
+			n++
+		}
+	}
+	return dst[:n]
+}
+
+func EmptyValueMap() ValueMap {
+	return newImmutableValueMap([]valueMapEntry{})
+}
+
+/**
+	Creates map from alternating keys and values in this order, missing last value is Null,
+	later pairs replace values of equal keys
+*/
+
+func ValueMapOf(pairs ...Value) ValueMap {
+	list := make([]valueMapEntry, 0, (len(pairs) + 1) / 2)
+	for i := 0; i < len(pairs); i += 2 {
+		e := valueMapEntry{key: nullIfNil(pairs[i]), value: Null}
+		if i + 1 < len(pairs) {
+			e.value = nullIfNil(pairs[i+1])
+		}
+		list = append(list, e)
+	}
+	return newImmutableValueMap(list)
+}
+
+func nullIfNil(val Value) Value {
+	if val == nil {
+		return Null
+	}
+	return val
+}
+
+func (t immutableValueMap) Kind() Kind {
+	return MAP
+}
+
+func (t immutableValueMap) Class() reflect.Type {
+	return immutableValueMapClass
+}
+
+func (t immutableValueMap) Object() interface{} {
+	pairs := make([][2]Value, len(t.list))
+	for i, e := range t.list {
+		pairs[i] = [2]Value{ e.key, e.value }
+	}
+	return pairs
+}
+
+func (t immutableValueMap) String() string {
+	var out strings.Builder
+	t.PrintJSON(&out)
+	return out.String()
+}
+
+func (t immutableValueMap) Pack(p Packer) {
+	t.memo.pack(p, t.immutableChildren, t.pack)
+}
+
+func (t immutableValueMap) pack(p Packer) {
+
+	p.PackMap(len(t.list))
+
+	for _, e := range t.list {
+		e.key.Pack(p)
+		e.value.Pack(p)
+	}
+
+}
+
+func (t immutableValueMap) PrintJSON(out *strings.Builder) {
+
+	out.WriteRune('{')
+	for i, e := range t.list {
+		if i != 0 {
+			out.WriteRune(',')
+		}
+		out.WriteRune(jsonQuote)
+		out.WriteString(e.key.String())
+		out.WriteRune(jsonQuote)
+
+		out.WriteString(": ")
+		e.value.PrintJSON(out)
+	}
+	out.WriteRune('}')
+}
+
+func (t immutableValueMap) MarshalJSON() ([]byte, error) {
+	var out strings.Builder
+	t.PrintJSON(&out)
+	return []byte(out.String()), nil
+}
+
+func (t immutableValueMap) MarshalBinary() ([]byte, error) {
+	buf := bytes.Buffer{}
+	p := MessagePacker(&buf)
+	t.Pack(p)
+	return buf.Bytes(), p.Error()
+}
+
+func (t immutableValueMap) Equal(val Value) bool {
+	if val == nil || val.Kind() != MAP {
+		return false
+	}
+	keys, values := mapPairs(val.(Map))
+	if len(keys) != len(t.list) {
+		return false
+	}
+	for i, j := range t.index {
+		e := t.list[j]
+		if !e.key.Equal(keys[i]) || !Equal(e.value, values[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+/**
+	Keys and values of any map in the Compare order of keys, string keys of Map become Utf8
+*/
+
+func mapPairs(m Map) ([]Value, []Value) {
+	if vm, ok := m.(immutableValueMap); ok {
+		keys := make([]Value, len(vm.index))
+		values := make([]Value, len(vm.index))
+		for i, j := range vm.index {
+			keys[i], values[i] = vm.list[j].key, vm.list[j].value
+		}
+		return keys, values
+	}
+	entries := m.Entries()
+	keys := make([]Value, len(entries))
+	values := make([]Value, len(entries))
+	for i, e := range entries {
+		keys[i], values[i] = Utf8(e.Key()), e.Value()
+	}
+	return keys, values
+}
+
+func (t immutableValueMap) Len() int {
+	return len(t.list)
+}
+
+func (t immutableValueMap) Entries() []MapEntry {
+	entries := make([]MapEntry, len(t.list))
+	for i, e := range t.list {
+		entries[i] = ImmutableEntry(e.key.String(), e.value)
+	}
+	return entries
+}
+
+func (t immutableValueMap) HashMap() map[string]Value {
+	cache := make(map[string]Value)
+	for _, e := range t.list {
+		cache[e.key.String()] = e.value
+	}
+	return cache
+}
+
+func (t immutableValueMap) Keys() []string {
+	var keys []string
+	for _, e := range t.list {
+		keys = append(keys, e.key.String())
+	}
+	return keys
+}
+
+func (t immutableValueMap) KeyValues() []Value {
+	var keys []Value
+	for _, e := range t.list {
+		keys = append(keys, e.key)
+	}
+	return keys
+}
+
+func (t immutableValueMap) Values() []Value {
+	var values []Value
+	for _, e := range t.list {
+		values = append(values, e.value)
+	}
+	return values
+}
+
+/**
+	Returns position of the entry with the key and true if key found
+*/
+
+func (t immutableValueMap) search(key Value) (int, bool) {
+	n := len(t.index)
+	i := sort.Search(n, func(i int) bool {
+		return Compare(t.list[t.index[i]].key, key) >= 0
+	})
+	if i < n && Compare(t.list[t.index[i]].key, key) == 0 {
+		return t.index[i], true
+	}
+	return -1, false
+}
+
+func (t immutableValueMap) GetKey(key Value) Value {
+	if i, ok := t.search(key); ok {
+		return t.list[i].value
+	}
+	return Null
+}
+
+func (t immutableValueMap) HasKey(key Value) bool {
+	_, ok := t.search(key)
+	return ok
+}
+
+func (t immutableValueMap) Get(key string) Value {
+	return t.GetKey(Utf8(key))
+}
+
+func (t immutableValueMap) GetBool(key string) Bool {
+	value := t.Get(key)
+	if value != Null {
+		if value.Kind() == BOOL {
+			return value.(Bool)
+		}
+		return ParseBoolean(value.String())
+	}
+	return False
+}
+
+func (t immutableValueMap) GetNumber(key string) Number {
+	value := t.Get(key)
+	if value != Null {
+		if value.Kind() == NUMBER {
+			return value.(Number)
+		}
+		return ParseNumber(value.String())
+	}
+	return Zero
+}
+
+func (t immutableValueMap) GetString(key string) String {
+	value := t.Get(key)
+	if value != Null {
+		if value.Kind() == STRING {
+			return value.(String)
+		}
+		return ParseString(value.String())
+	}
+	return EmptyString
+}
+
+func (t immutableValueMap) GetList(key string) List {
+	value := t.Get(key)
+	if value != Null {
+		switch value.Kind() {
+		case LIST:
+			return value.(List)
+		case MAP:
+			return ImmutableList(value.(Map).Values())
+		}
+	}
+	return EmptyImmutableList()
+}
+
+func (t immutableValueMap) GetMap(key string) Map {
+	value := t.Get(key)
+	if value != Null {
+		switch value.Kind() {
+		case LIST:
+			return ImmutableMap(value.(List).Entries(), false)
+		case MAP:
+			return value.(Map)
+		}
+	}
+	return EmptyImmutableMap()
+}
+
+func (t immutableValueMap) PutKey(key Value, value Value) ValueMap {
+	key, value = nullIfNil(key), nullIfNil(value)
+	i, ok := t.search(key)
+	if ok {
+		dst := make([]valueMapEntry, len(t.list))
+		copy(dst, t.list)
+		dst[i] = valueMapEntry{key, value}
+		return newImmutableValueMap(dst)
+	}
+	dst := make([]valueMapEntry, len(t.list)+1)
+	copy(dst, t.list)
+	dst[len(t.list)] = valueMapEntry{key, value}
+	return newImmutableValueMap(dst)
+}
+
+func (t immutableValueMap) RemoveKey(key Value) ValueMap {
+	i, ok := t.search(key)
+	if !ok {
+		return t
+	}
+	dst := make([]valueMapEntry, len(t.list)-1)
+	copy(dst, t.list[:i])
+	copy(dst[i:], t.list[i+1:])
+	return newImmutableValueMap(dst)
+}
+
+func (t immutableValueMap) Insert(key string, value Value) Map {
+	return t.Put(key, value)
+}
+
+func (t immutableValueMap) Put(key string, value Value) Map {
+	return t.PutKey(Utf8(key), value)
+}
+
+func (t immutableValueMap) Update(key string, updater Updater) bool {
+	return false
+}
+
+func (t immutableValueMap) Remove(key string) Map {
+	return t.RemoveKey(Utf8(key))
+}
+
+func (t immutableValueMap) Select(key string) []Value {
+	if i, ok := t.search(Utf8(key)); ok {
+		return []Value{ t.list[i].value }
+	}
+	return nil
+}
+
+func (t immutableValueMap) InsertAll(key string, list []Value) Map {
+	if len(list) == 0 {
+		return t
+	}
+	return t.Put(key, list[len(list)-1])
+}
+
+func (t immutableValueMap) DeleteAll(key string) Map {
+	return t.Remove(key)
+}
+
+func (t immutableValueMap) immutableChildren() bool {
+	for _, e := range t.list {
+		if !isImmutable(e.key) || !isImmutable(e.value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t immutableValueMap) hashCode() uint64 {
+	m := t.memo.load()
+	if m == nil || !m.immutable(t.immutableChildren) {
+		return computeHashCode(t)
+	}
+	m.hashOnce.Do(func() {
+		m.hash = computeHashCode(t)
+	})
+	return m.hash
+}
+
+func (t immutableValueMap) digest(hash crypto.Hash, canonical bool) ([]byte, []byte) {
+	return t.memo.load().digest(hash, canonical, t.immutableChildren, t.pack)
+}
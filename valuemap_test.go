@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2023 Zander Schwid & Co. LLC.
+ * SPDX-License-Identifier: BUSL-1.1
+ */
+
+package value_test
+
+import (
+	"encoding/hex"
+	"testing"
+	val "github.com/codeallergy/value"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueMapUnpack(t *testing.T) {
+
+	for _, in := range []string {
+		"82c202c301",                 // bool keys
+		"82c4016101c4016202",         // binary keys
+		"8201a161a16202",             // mixed number and string keys
+		"81cb3ff800000000000001",     // double key
+		"82c0c3a161c2",               // nil key
+	} {
+		mp, _ := hex.DecodeString(in)
+		v, err := val.Unpack(mp, false)
+		require.NoError(t, err, in)
+		require.Equal(t, val.MAP, v.Kind(), in)
+		_, ok := v.(val.ValueMap)
+		require.True(t, ok, in)
+		require.Equal(t, in, val.Hex(v))
+	}
+
+	// integer and string keys keep the old types
+	mp, _ := hex.DecodeString("8201020202a162")
+	v, err := val.Unpack(mp, false)
+	require.NoError(t, err)
+	require.Equal(t, val.LIST, v.Kind())
+
+	mp, _ = hex.DecodeString("82a162c3a161c2")
+	v, err = val.Unpack(mp, false)
+	require.NoError(t, err)
+	require.Equal(t, "value.immutableMapValue", v.Class().String())
+
+	// wire order is kept, lookup and equality do not depend on it
+	mp, _ = hex.DecodeString("82c301c202")
+	v, err = val.Unpack(mp, false)
+	require.NoError(t, err)
+	require.Equal(t, "82c301c202", val.Hex(v))
+	require.Equal(t, int64(2), v.(val.ValueMap).GetKey(val.False).(val.Number).Long())
+
+	mp, _ = hex.DecodeString("82c202c301")
+	other, err := val.Unpack(mp, false)
+	require.NoError(t, err)
+	require.True(t, v.Equal(other))
+	require.Equal(t, val.HashCode(v), val.HashCode(other))
+	require.Equal(t, 0, val.Compare(v, other))
+
+	// duplicate keys, the first key with the last value
+	mp, _ = hex.DecodeString("83c301c202c303")
+	v, err = val.Unpack(mp, false)
+	require.NoError(t, err)
+	require.Equal(t, 2, v.(val.Map).Len())
+	require.Equal(t, "82c303c202", val.Hex(v))
+
+}
+
+func TestValueMap(t *testing.T) {
+
+	m := val.ValueMapOf(val.Raw([]byte{1, 2}, false), val.Utf8("bin"), val.True, val.Long(1), val.Utf8("name"), val.Utf8("Bob"))
+	require.Equal(t, val.MAP, m.Kind())
+	require.Equal(t, 3, m.Len())
+
+	require.Equal(t, "bin", m.GetKey(val.Raw([]byte{1, 2}, false)).String())
+	require.Equal(t, int64(1), m.GetKey(val.True).(val.Number).Long())
+	require.Equal(t, "Bob", m.Get("name").String())
+	require.Equal(t, val.Null, m.GetKey(val.False))
+	require.True(t, m.HasKey(val.True))
+
+	m2 := m.PutKey(val.Long(7), val.Utf8("seven")).RemoveKey(val.True)
+	require.Equal(t, 3, m2.Len())
+	require.Equal(t, "seven", m2.GetKey(val.Double(7)).String())
+	require.False(t, m2.HasKey(val.True))
+	require.True(t, m.HasKey(val.True))
+
+	require.Equal(t, []val.Value{ val.Raw([]byte{1, 2}, false), val.True, val.Utf8("name") }, m.KeyValues())
+	require.Equal(t, []val.Value{ val.Raw([]byte{1, 2}, false), val.Utf8("name"), val.Long(7) }, m2.KeyValues())
+
+	mp, err := val.Pack(m)
+	require.NoError(t, err)
+	v, err := val.Unpack(mp, false)
+	require.NoError(t, err)
+	require.True(t, m.Equal(v))
+	require.True(t, v.Equal(m))
+	require.Equal(t, val.HashCode(m), val.HashCode(v))
+	require.Equal(t, 0, val.Compare(m, v))
+
+	// string keys are the same as in the plain map
+	strMap := val.ValueMapOf(val.Utf8("a"), val.Long(1))
+	plain := val.EmptyImmutableMap().Put("a", val.Long(1))
+	require.True(t, strMap.Equal(plain))
+	require.True(t, plain.Equal(strMap))
+	require.Equal(t, val.HashCode(plain), val.HashCode(strMap))
+	require.False(t, val.ValueMapOf(val.Long(1), val.Long(1)).Equal(val.EmptyImmutableMap().Put("1", val.Long(1))))
+	require.False(t, val.EmptyImmutableMap().Put("1", val.Long(1)).Equal(val.ValueMapOf(val.Long(1), val.Long(1))))
+
+}
+
+func TestValueMapDuplicateKeys(t *testing.T) {
+
+	m := val.ValueMapOf(val.Long(1), val.Utf8("a"), val.Utf8("k"), val.True, val.Double(1), val.Utf8("b"))
+	require.Equal(t, 2, m.Len())
+	require.Equal(t, "b", m.GetKey(val.Long(1)).String())
+	require.Equal(t, "b", m.GetKey(val.Double(1)).String())
+	require.Equal(t, []val.Value{ val.Long(1), val.Utf8("k") }, m.KeyValues())
+
+	// keys stay unique
+	m2 := m.Insert("k", val.False).InsertAll("k", []val.Value{ val.Long(5), val.Long(6) })
+	require.Equal(t, 2, m2.Len())
+	require.Equal(t, []val.Value{ val.Long(6) }, m2.Select("k"))
+	require.Equal(t, 1, m2.DeleteAll("k").Len())
+
+}